@@ -0,0 +1,357 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Knetic/govaluate"
+)
+
+const (
+	defaultCooldown = 300              // Default number of seconds before the alert can fire again.
+	bannerTimeout   = 60 * time.Second // How long the alert stays in the on-screen banner.
+)
+
+// AlertRule is the condition checked against every stock quote whenever the
+// quotes get fetched. The condition is a govaluate expression that uses the
+// same variables as the filter, ex. `ticker == 'AAPL' && last > 250`.
+type AlertRule struct {
+	Name       string                         // Rule name shown in notifications.
+	Expression string                         // Condition in human form.
+	Message    string                         `json:",omitempty"` // Optional notification text, defaults to the rule name.
+	Cooldown   int                            // Seconds before the rule can fire again for the same ticker.
+	Notify     []string                       // Notifiers to use: bell, flash, banner, command, and webhook.
+	Disabled   bool                           `json:",omitempty"` // True when the rule is not checked.
+	expression *govaluate.EvaluableExpression // The condition as a govaluate expression.
+}
+
+// AlertState is the persistent state of the alert rule for the particular
+// stock ticker.
+type AlertState struct {
+	Active    bool      // True while the condition holds.
+	LastFired time.Time // When the alert has fired last time.
+}
+
+// Alert gets passed to notifiers when the alert rule fires.
+type Alert struct {
+	Rule      string    `json:"rule"`          // Rule name.
+	Message   string    `json:"message"`       // Notification text.
+	Ticker    string    `json:"ticker"`        // Stock ticker that has triggered the alert.
+	Last      NullFloat `json:"last"`          // Last trade price of the stock.
+	Change    NullFloat `json:"change"`        // Change of the stock price.
+	ChangePct NullFloat `json:"changePercent"` // Change percent of the stock price.
+	Time      time.Time `json:"time"`          // When the alert has fired.
+}
+
+// Notifier delivers alerts to the user.
+type Notifier interface {
+	Notify(alert *Alert) error
+}
+
+// Alerts evaluates alert rules stored in the profile and sends fired alerts
+// to the notifiers. The alerts are edge-triggered: the rule fires when its
+// condition becomes true and then stays silent until the condition becomes
+// false and true again, and the cooldown period is over. The rules get
+// evaluated as the quotes get fetched in the background, so the state of
+// the rules is kept here and copied to the profile by Save.
+type Alerts struct {
+	sync.Mutex
+	profile   *Profile               // Pointer to Profile where we keep alert rules and state.
+	state     map[string]*AlertState // State of alert rules keyed by rule name and ticker.
+	changed   bool                   // True when the state has changed since it was saved.
+	notifiers map[string]Notifier    // Notifiers by name.
+	banner    []*Alert               // Recently fired alerts to show on the screen.
+	errors    string                 // Error(s), if any.
+	muted     bool                   // True when the rules are not checked at all.
+}
+
+// Returns new Alerts struct with the notifiers that don't require screen
+// access. The command and webhook notifiers are only available when
+// configured in the profile.
+func NewAlerts(profile *Profile) *Alerts {
+	alerts := &Alerts{profile: profile, state: copyState(profile.AlertState), notifiers: make(map[string]Notifier)}
+
+	alerts.notifiers[`bell`] = &BellNotifier{}
+	alerts.notifiers[`banner`] = alerts
+	if profile.Notifiers.Command != `` {
+		alerts.notifiers[`command`] = &CommandNotifier{Command: profile.Notifiers.Command}
+	}
+	if profile.Notifiers.Webhook != `` {
+		alerts.notifiers[`webhook`] = NewWebhookNotifier(profile.Notifiers.Webhook)
+	}
+
+	return alerts
+}
+
+// AddNotifier registers notifier under the given name.
+func (alerts *Alerts) AddNotifier(name string, notifier Notifier) *Alerts {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	alerts.notifiers[name] = notifier
+	return alerts
+}
+
+// Mute stops checking the alert rules so that fetching the quotes neither
+// notifies nor changes the alerts state, ex. in the one-shot mode.
+func (alerts *Alerts) Mute() *Alerts {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	alerts.muted = true
+	return alerts
+}
+
+// Add creates new alert rule for the given expression.
+func (alerts *Alerts) Add(expression string) error {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	return alerts.profile.AddAlert(expression)
+}
+
+// Remove deletes the alert rule with the given name.
+func (alerts *Alerts) Remove(name string) error {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	for key := range alerts.state {
+		if strings.HasPrefix(key, name+`|`) {
+			delete(alerts.state, key)
+		}
+	}
+	return alerts.profile.RemoveAlert(name)
+}
+
+// Save copies the state of the alert rules to the profile and saves it if
+// the state has changed. Unlike Evaluate it must be called from the same
+// goroutine that changes the profile.
+func (alerts *Alerts) Save() error {
+	alerts.Lock()
+	if !alerts.changed {
+		alerts.Unlock()
+		return nil
+	}
+	alerts.profile.AlertState = copyState(alerts.state)
+	alerts.changed = false
+	alerts.Unlock()
+
+	return alerts.profile.Save()
+}
+
+// Evaluate checks all the alert rules against the given stock quotes and
+// notifies about the rules that fire. The profile is not saved, see Save.
+func (alerts *Alerts) Evaluate(stocks []Stock) {
+	alerts.Lock()
+	profile := alerts.profile
+	if len(profile.Alerts) == 0 || alerts.muted {
+		alerts.Unlock()
+		return
+	}
+
+	var fired []*Alert
+	var notify [][]string
+	var errors []string
+	now := time.Now()
+
+	for _, rule := range profile.Alerts {
+		if rule.Disabled {
+			continue
+		}
+		if err := rule.compile(); err != nil {
+			errors = append(errors, fmt.Sprintf("alert `%s`: %v", rule.Name, err))
+			continue
+		}
+		for i := range stocks {
+			stock := &stocks[i]
+			result, err := rule.expression.Evaluate(filterValues(stock))
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("alert `%s`: %v", rule.Name, err))
+				break
+			}
+			active, _ := result.(bool)
+			key := rule.Name + `|` + stock.Ticker
+			state, ok := alerts.state[key]
+			if !ok {
+				if !active {
+					continue
+				}
+				state = &AlertState{}
+				alerts.state[key] = state
+			}
+			if active && !state.Active && now.Sub(state.LastFired) >= rule.cooldown() {
+				state.LastFired = now
+				fired = append(fired, rule.alert(stock, now))
+				notify = append(notify, rule.Notify)
+				alerts.changed = true
+			}
+			if active != state.Active {
+				state.Active = active
+				alerts.changed = true
+			}
+		}
+	}
+	alerts.errors = strings.Join(errors, ` | `)
+	notifiers := alerts.notifiers
+	alerts.Unlock()
+
+	for i, alert := range fired {
+		names := notify[i]
+		if len(names) == 0 {
+			names = []string{`bell`, `banner`}
+		}
+		for _, name := range names {
+			notifier, ok := notifiers[name]
+			if !ok {
+				alerts.error(fmt.Sprintf("alert `%s`: unknown notifier `%s`", alert.Rule, name))
+				continue
+			}
+			if err := notifier.Notify(alert); err != nil {
+				alerts.error(fmt.Sprintf("alert `%s`: %s: %v", alert.Rule, name, err))
+			}
+		}
+	}
+}
+
+// Notify implements the on-screen banner notifier: the alert is shown at the
+// top of the stock quotes list for a while.
+func (alerts *Alerts) Notify(alert *Alert) error {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	alerts.banner = append(alerts.banner, alert)
+	return nil
+}
+
+// Banner returns formatted list of recently fired alerts.
+func (alerts *Alerts) Banner() string {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	var messages []string
+	recent := alerts.banner[:0]
+	for _, alert := range alerts.banner {
+		if time.Since(alert.Time) < bannerTimeout {
+			recent = append(recent, alert)
+			messages = append(messages, alert.Ticker+`: `+alert.Message)
+		}
+	}
+	alerts.banner = recent
+
+	return strings.Join(messages, ` | `)
+}
+
+// Ok returns two values: 1) boolean indicating whether the error has occurred,
+// and 2) the error text itself.
+func (alerts *Alerts) Ok() (bool, string) {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	return alerts.errors == ``, alerts.errors
+}
+
+// -----------------------------------------------------------------------------
+func (alerts *Alerts) error(message string) {
+	alerts.Lock()
+	defer alerts.Unlock()
+
+	if alerts.errors != `` {
+		alerts.errors += ` | `
+	}
+	alerts.errors += message
+}
+
+// AddAlert creates new alert rule with default cooldown and notifiers. The
+// expression itself serves as the rule name.
+func (profile *Profile) AddAlert(expression string) error {
+	rule := &AlertRule{
+		Name:       strings.TrimSpace(expression),
+		Expression: strings.TrimSpace(expression),
+		Cooldown:   defaultCooldown,
+		Notify:     []string{`bell`, `banner`},
+	}
+	if err := rule.compile(); err != nil {
+		return err
+	}
+	for _, existing := range profile.Alerts {
+		if existing.Name == rule.Name {
+			return fmt.Errorf("alert `%s` already exists", rule.Name)
+		}
+	}
+
+	profile.Alerts = append(profile.Alerts, rule)
+	return profile.Save()
+}
+
+// RemoveAlert deletes the alert rule with the given name along with its
+// state.
+func (profile *Profile) RemoveAlert(name string) error {
+	for i, rule := range profile.Alerts {
+		if rule.Name == name {
+			profile.Alerts = append(profile.Alerts[:i], profile.Alerts[i+1:]...)
+			for key := range profile.AlertState {
+				if strings.HasPrefix(key, name+`|`) {
+					delete(profile.AlertState, key)
+				}
+			}
+			return profile.Save()
+		}
+	}
+	return fmt.Errorf("no alert `%s`", name)
+}
+
+// Returns the copy of the alert rules state that can be changed without
+// affecting the original.
+// -----------------------------------------------------------------------------
+func copyState(state map[string]*AlertState) map[string]*AlertState {
+	copied := make(map[string]*AlertState, len(state))
+	for key, value := range state {
+		value := *value
+		copied[key] = &value
+	}
+	return copied
+}
+
+// -----------------------------------------------------------------------------
+func (rule *AlertRule) compile() error {
+	if rule.expression != nil {
+		return nil
+	}
+	expr, err := compileExpression(rule.Expression)
+	if err != nil {
+		return err
+	}
+	if err := validateFilter(expr); err != nil {
+		return err
+	}
+	rule.expression = expr
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+func (rule *AlertRule) cooldown() time.Duration {
+	return time.Duration(rule.Cooldown) * time.Second
+}
+
+// -----------------------------------------------------------------------------
+func (rule *AlertRule) alert(stock *Stock, now time.Time) *Alert {
+	message := rule.Message
+	if message == `` {
+		message = rule.Name
+	}
+	return &Alert{
+		Rule:      rule.Name,
+		Message:   message,
+		Ticker:    stock.Ticker,
+		Last:      stock.LastTrade,
+		Change:    stock.Change,
+		ChangePct: stock.ChangePct,
+		Time:      now,
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recorder is the notifier that remembers the alerts.
+type recorder []*Alert
+
+func (r *recorder) Notify(alert *Alert) error {
+	*r = append(*r, alert)
+	return nil
+}
+
+func TestAlertsEvaluate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `moprc`)
+	profile := &Profile{filename: filename}
+	profile.Alerts = []*AlertRule{{Name: `high`, Expression: `last > 200`, Cooldown: 300, Notify: []string{`test`}}}
+	alerts := NewAlerts(profile)
+	fired := &recorder{}
+	alerts.AddNotifier(`test`, fired)
+
+	tests := []struct {
+		last  float64
+		fired int // Total number of alerts fired so far.
+	}{
+		{190, 0},
+		{210, 1},
+		{220, 1}, // Still above, the alert doesn't fire again.
+		{180, 1},
+		{230, 1}, // Above again but the cooldown period isn't over.
+	}
+	for i, test := range tests {
+		alerts.Evaluate([]Stock{{Ticker: `AAPL`, LastTrade: Float(test.last)}, {Ticker: `MSFT`, LastTrade: Float(100)}})
+		if len(*fired) != test.fired {
+			t.Errorf(`step %d: got %d alerts, want %d`, i+1, len(*fired), test.fired)
+		}
+	}
+	if ok, err := alerts.Ok(); !ok {
+		t.Errorf(`got error %s`, err)
+	}
+
+	// The profile only gets saved by Save.
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf(`profile has been saved by Evaluate`)
+	}
+	if profile.AlertState != nil {
+		t.Errorf(`profile state has been changed by Evaluate: %v`, profile.AlertState)
+	}
+	if err := alerts.Save(); err != nil {
+		t.Fatal(err)
+	}
+	state := profile.AlertState[`high|AAPL`]
+	if state == nil || !state.Active || state.LastFired.IsZero() {
+		t.Errorf(`got state %+v`, state)
+	}
+	if _, ok := profile.AlertState[`high|MSFT`]; ok {
+		t.Errorf(`got state of the ticker that has never been active`)
+	}
+	if data, err := ioutil.ReadFile(filename); err != nil || len(data) == 0 {
+		t.Errorf(`profile hasn't been saved: %v`, err)
+	}
+
+	// Unchanged state doesn't get saved again.
+	os.Remove(filename)
+	alerts.Evaluate([]Stock{{Ticker: `AAPL`, LastTrade: Float(230)}})
+	if err := alerts.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf(`unchanged state has been saved`)
+	}
+}
+
+func TestAlertsRemove(t *testing.T) {
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `moprc`)}
+	alerts := NewAlerts(profile)
+	alerts.AddNotifier(`test`, &recorder{})
+	if err := alerts.Add(`last > 200`); err != nil {
+		t.Fatal(err)
+	}
+	profile.Alerts[0].Notify = []string{`test`}
+	alerts.Evaluate([]Stock{{Ticker: `AAPL`, LastTrade: Float(210)}})
+	alerts.Save()
+
+	if err := alerts.Remove(`last > 200`); err != nil {
+		t.Fatal(err)
+	}
+	alerts.Save()
+	if len(profile.Alerts) != 0 || len(profile.AlertState) != 0 || len(alerts.state) != 0 {
+		t.Errorf(`got alerts %v and state %v`, profile.Alerts, profile.AlertState)
+	}
+	if err := alerts.Remove(`last > 200`); err == nil {
+		t.Errorf(`removing unknown alert should fail`)
+	}
+}
+
+func TestAlertsMute(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `moprc`)
+	profile := &Profile{filename: filename}
+	profile.Alerts = []*AlertRule{{Name: `high`, Expression: `last > 200`, Notify: []string{`test`}}}
+	fired := &recorder{}
+	alerts := NewAlerts(profile).AddNotifier(`test`, fired).Mute()
+
+	alerts.Evaluate([]Stock{{Ticker: `AAPL`, LastTrade: Float(210)}})
+	if len(*fired) != 0 {
+		t.Errorf(`muted alerts have fired: %v`, *fired)
+	}
+	if err := alerts.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf(`profile has been saved by muted alerts`)
+	}
+}
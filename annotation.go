@@ -0,0 +1,118 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import "strings"
+
+// Annotation is what the user has written down about the stock.
+type Annotation struct {
+	Note  string   // Free form note shown in the stock details.
+	Tags  []string `json:",omitempty"` // Tags to filter and group the stocks by, ex. semis.
+	Alias string   `json:",omitempty"` // Name shown in the Ticker column instead of the ticker, ex. ASML for ASML.AS.
+}
+
+// Optional columns that show the annotations. Like the Yahoo quote fields
+// they are hidden unless the user chooses to show them.
+var annotationColumns = []Column{
+	{10, `Alias`, `Alias`, text, nil},
+	{16, `Tags`, `Tags`, text, nil},
+	{24, `Note`, `Note`, text, nil},
+}
+
+// Annotation returns the annotation of the given stock ticker, or nil if
+// there is none.
+func (profile *Profile) Annotation(ticker string) *Annotation {
+	return profile.Annotations[ticker]
+}
+
+// SetNote saves the note about the given stock. Blank note removes it.
+func (profile *Profile) SetNote(ticker, note string) error {
+	return profile.annotate(ticker, func(annotation *Annotation) {
+		annotation.Note = strings.TrimSpace(note)
+	})
+}
+
+// SetTags parses the list of tags separated by commas or blanks, and saves
+// them along with the alias prefixed by @, ex. `semis, growth @ASML`. Blank
+// list removes the tags and the alias.
+func (profile *Profile) SetTags(ticker, input string) error {
+	var tags []string
+	alias := ``
+	for _, word := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if strings.HasPrefix(word, `@`) {
+			alias = strings.TrimPrefix(word, `@`)
+		} else if !hasTag(tags, word) {
+			tags = append(tags, word)
+		}
+	}
+
+	return profile.annotate(ticker, func(annotation *Annotation) {
+		annotation.Tags, annotation.Alias = tags, alias
+	})
+}
+
+// String formats the tags and the alias the way SetTags parses them.
+func (annotation *Annotation) String() string {
+	str := strings.Join(annotation.Tags, `, `)
+	if annotation.Alias != `` {
+		if str != `` {
+			str += ` `
+		}
+		str += `@` + annotation.Alias
+	}
+	return str
+}
+
+// annotate copies the annotations of the stocks to the stock quotes so that
+// they could be shown, filtered, and sorted by. The annotations that have
+// been removed since the last time are cleared.
+func annotate(stocks []Stock, profile *Profile) {
+	for i := range stocks {
+		annotation := profile.Annotation(stocks[i].Ticker)
+		if annotation == nil {
+			annotation = &Annotation{}
+		}
+		stocks[i].Note = annotation.Note
+		stocks[i].Tags = strings.Join(annotation.Tags, `, `)
+		stocks[i].Alias = annotation.Alias
+	}
+}
+
+// Updates the annotation of the given stock creating it if necessary, and
+// removes it if nothing is left.
+// -----------------------------------------------------------------------------
+func (profile *Profile) annotate(ticker string, update func(*Annotation)) error {
+	annotation := profile.Annotations[ticker]
+	if annotation == nil {
+		annotation = &Annotation{}
+	}
+	update(annotation)
+	if annotation.empty() {
+		delete(profile.Annotations, ticker)
+	} else {
+		if profile.Annotations == nil {
+			profile.Annotations = make(map[string]*Annotation)
+		}
+		profile.Annotations[ticker] = annotation
+	}
+
+	return profile.Save()
+}
+
+// -----------------------------------------------------------------------------
+func (annotation *Annotation) empty() bool {
+	return annotation.Note == `` && len(annotation.Tags) == 0 && annotation.Alias == ``
+}
+
+// Returns true if the list of tags contains the given one ignoring case.
+// -----------------------------------------------------------------------------
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
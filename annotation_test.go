@@ -0,0 +1,38 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	profile := &Profile{filename: filepath.Join(t.TempDir(), `moprc`)}
+	if err := profile.SetTags(`ASML.AS`, `semis, europe @ASML`); err != nil {
+		t.Fatal(err)
+	}
+	stocks := []Stock{{Ticker: `ASML.AS`}, {Ticker: `AAPL`, Note: `stale`, Tags: `stale`, Alias: `stale`}}
+
+	annotate(stocks, profile)
+	if stock := stocks[0]; stock.Tags != `semis, europe` || stock.Alias != `ASML` {
+		t.Errorf(`got tags %q and alias %q`, stock.Tags, stock.Alias)
+	}
+	if stock := stocks[1]; stock.Note != `` || stock.Tags != `` || stock.Alias != `` {
+		t.Errorf(`stock without annotation got note %q, tags %q, and alias %q`, stock.Note, stock.Tags, stock.Alias)
+	}
+
+	// Removing the tags and the alias removes the annotation altogether.
+	if err := profile.SetTags(`ASML.AS`, ``); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Annotation(`ASML.AS`) != nil {
+		t.Fatalf(`got annotation %v, want none`, profile.Annotation(`ASML.AS`))
+	}
+	annotate(stocks, profile)
+	if stock := stocks[0]; stock.Tags != `` || stock.Alias != `` {
+		t.Errorf(`got tags %q and alias %q after they have been removed`, stock.Tags, stock.Alias)
+	}
+}
@@ -0,0 +1,461 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Exchange time zones must be known even if the system lacks zoneinfo.
+)
+
+// Trading session states.
+const (
+	SessionPre     = `pre`     // Pre-market trading.
+	SessionRegular = `regular` // Regular trading hours.
+	SessionBreak   = `break`   // Lunch break during regular trading hours.
+	SessionPost    = `post`    // After hours trading.
+	SessionClosed  = `closed`  // No trading.
+)
+
+// Exchange describes the trading hours of the stock exchange. The hours are
+// set in minutes since midnight of the exchange local time.
+type Exchange struct {
+	Name       string                     // Exchange name, ex. NYSE.
+	Timezone   string                     // IANA time zone name, ex. America/New_York.
+	PreOpen    int                        // Start of pre-market trading, same as Open if there is none.
+	Open       int                        // Start of regular trading hours.
+	BreakStart int                        // Start of the lunch break, zero if there is none.
+	BreakEnd   int                        // End of the lunch break.
+	Close      int                        // End of regular trading hours.
+	EarlyClose int                        // End of regular trading hours on half-days.
+	PostClose  int                        // End of after hours trading, same as Close if there is none.
+	holidays   func(year int) []time.Time // Returns the exchange holidays of the given year.
+	halfDays   func(year int) []time.Time // Returns the exchange half-days of the given year.
+	location   *time.Location             // Exchange time zone.
+	once       sync.Once                  // Loads the time zone on first use.
+}
+
+// nyse describes both NYSE and NASDAQ since they share the trading hours and
+// holidays.
+var nyse = &Exchange{
+	Name:       `NYSE`,
+	Timezone:   `America/New_York`,
+	PreOpen:    4 * 60,
+	Open:       9*60 + 30,
+	Close:      16 * 60,
+	EarlyClose: 13 * 60,
+	PostClose:  20 * 60,
+	holidays:   usHolidays,
+	halfDays:   usHalfDays,
+}
+
+var lse = &Exchange{
+	Name:       `LSE`,
+	Timezone:   `Europe/London`,
+	PreOpen:    8 * 60,
+	Open:       8 * 60,
+	Close:      16*60 + 30,
+	EarlyClose: 12*60 + 30,
+	PostClose:  16*60 + 30,
+	holidays:   ukHolidays,
+	halfDays:   christmasHalfDays,
+}
+
+var xetra = &Exchange{
+	Name:      `Xetra`,
+	Timezone:  `Europe/Berlin`,
+	PreOpen:   9 * 60,
+	Open:      9 * 60,
+	Close:     17*60 + 30,
+	PostClose: 17*60 + 30,
+	holidays:  germanHolidays,
+}
+
+var euronext = &Exchange{
+	Name:       `Euronext`,
+	Timezone:   `Europe/Paris`,
+	PreOpen:    9 * 60,
+	Open:       9 * 60,
+	Close:      17*60 + 30,
+	EarlyClose: 14*60 + 5,
+	PostClose:  17*60 + 30,
+	holidays:   euronextHolidays,
+	halfDays:   christmasHalfDays,
+}
+
+var tse = &Exchange{
+	Name:       `TSE`,
+	Timezone:   `Asia/Tokyo`,
+	PreOpen:    9 * 60,
+	Open:       9 * 60,
+	BreakStart: 11*60 + 30,
+	BreakEnd:   12*60 + 30,
+	Close:      15*60 + 30,
+	PostClose:  15*60 + 30,
+	holidays:   japanHolidays,
+}
+
+// Exchanges by ticker suffix, ex. `L` for BARC.L. Tickers without the suffix
+// are traded in the U.S. unless they are currency pairs, see ExchangeFor.
+var exchanges = map[string]*Exchange{
+	``:   nyse,
+	`L`:  lse,
+	`DE`: xetra,
+	`F`:  xetra,
+	`PA`: euronext,
+	`AS`: euronext,
+	`BR`: euronext,
+	`LS`: euronext,
+	`T`:  tse,
+}
+
+// Exchanges of the market indices.
+var indices = map[string]*Exchange{
+	`^DJI`:   nyse,
+	`^GSPC`:  nyse,
+	`^IXIC`:  nyse,
+	`^RUT`:   nyse,
+	`^FTSE`:  lse,
+	`^GDAXI`: xetra,
+	`^FCHI`:  euronext,
+	`^AEX`:   euronext,
+	`^N225`:  tse,
+}
+
+// ExchangeFor returns the exchange the stock is traded on as implied by the
+// ticker suffix, or nil if the exchange is not known. Crypto and currency
+// pairs, ex. BTC-USD or EURUSD=X, trade around the clock so they have no
+// exchange.
+func ExchangeFor(ticker string) *Exchange {
+	if strings.ContainsAny(ticker, `^=`) {
+		return indices[ticker]
+	}
+	suffix := suffix(ticker)
+	if suffix == `` && currencyPair(ticker) {
+		return nil
+	}
+	return exchanges[suffix]
+}
+
+// Session returns the trading session of the exchange at the given time.
+func (exchange *Exchange) Session(t time.Time) string {
+	local := t.In(exchange.zone())
+	if !exchange.IsTradingDay(local) {
+		return SessionClosed
+	}
+
+	closing, postClosing := exchange.Close, exchange.PostClose
+	if exchange.IsHalfDay(local) {
+		closing, postClosing = exchange.EarlyClose, exchange.PostClose-(exchange.Close-exchange.EarlyClose)
+	}
+
+	minutes := local.Hour()*60 + local.Minute()
+	switch {
+	case minutes < exchange.PreOpen:
+		return SessionClosed
+	case minutes < exchange.Open:
+		return SessionPre
+	case minutes >= exchange.BreakStart && minutes < exchange.BreakEnd && minutes < closing:
+		return SessionBreak
+	case minutes < closing:
+		return SessionRegular
+	case minutes < postClosing:
+		return SessionPost
+	}
+	return SessionClosed
+}
+
+// IsTradingDay returns true unless the given day is a weekend or a holiday
+// at the exchange.
+func (exchange *Exchange) IsTradingDay(t time.Time) bool {
+	local := t.In(exchange.zone())
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	return !contains(exchange.holidays, local)
+}
+
+// IsHalfDay returns true if the exchange closes early on the given day.
+func (exchange *Exchange) IsHalfDay(t time.Time) bool {
+	return exchange.halfDays != nil && contains(exchange.halfDays, t.In(exchange.zone()))
+}
+
+// NextSession returns the time when the next trading session, pre-market
+// included, starts after the given time.
+func (exchange *Exchange) NextSession(t time.Time) time.Time {
+	local := t.In(exchange.zone())
+	for i := 0; i < 366; i++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()+i,
+			exchange.PreOpen/60, exchange.PreOpen%60, 0, 0, exchange.zone())
+		if start.After(t) && exchange.IsTradingDay(start) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// -----------------------------------------------------------------------------
+func (exchange *Exchange) zone() *time.Location {
+	exchange.once.Do(func() {
+		location, err := time.LoadLocation(exchange.Timezone)
+		if err != nil {
+			location = time.UTC
+		}
+		exchange.location = location
+	})
+	return exchange.location
+}
+
+// closedWait returns how long to wait before fetching the data again when
+// the exchanges are closed: closedRefresh or till the next trading session,
+// whichever comes first. Negative closedRefresh waits for the next session.
+// Zero next session time means it's not known and closedRefresh is used
+// (one hour if it's negative).
+// -----------------------------------------------------------------------------
+func closedWait(closedRefresh time.Duration, now, next time.Time) time.Duration {
+	wait := closedRefresh
+	if next.IsZero() {
+		if wait < 0 {
+			wait = time.Hour
+		}
+	} else if until := next.Sub(now); wait < 0 || until < wait {
+		wait = until
+	}
+
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// usHolidays returns NYSE holidays of the given year. The holidays falling
+// on Sunday are observed on Monday, and the ones falling on Saturday are
+// observed on Friday except for the New Year's Day.
+// -----------------------------------------------------------------------------
+func usHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		nthWeekday(year, time.January, time.Monday, 3),    // Martin Luther King Jr. Day.
+		nthWeekday(year, time.February, time.Monday, 3),   // Washington's Birthday.
+		easter(year).AddDate(0, 0, -2),                    // Good Friday.
+		nthWeekday(year, time.May, time.Monday, -1),       // Memorial Day.
+		observed(date(year, time.July, 4)),                // Independence Day.
+		nthWeekday(year, time.September, time.Monday, 1),  // Labor Day.
+		nthWeekday(year, time.November, time.Thursday, 4), // Thanksgiving Day.
+		observed(date(year, time.December, 25)),           // Christmas Day.
+	}
+	if newYear := date(year, time.January, 1); newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, observed(newYear))
+	}
+	if year >= 2022 {
+		holidays = append(holidays, observed(date(year, time.June, 19))) // Juneteenth.
+	}
+
+	return holidays
+}
+
+// NYSE closes early on the day before Independence Day, the day after
+// Thanksgiving, and on Christmas Eve.
+// -----------------------------------------------------------------------------
+func usHalfDays(year int) []time.Time {
+	halfDays := []time.Time{nthWeekday(year, time.November, time.Thursday, 4).AddDate(0, 0, 1)}
+	if july3 := date(year, time.July, 3); july3.Weekday() >= time.Monday && july3.Weekday() <= time.Thursday {
+		halfDays = append(halfDays, july3)
+	}
+	return append(halfDays, date(year, time.December, 24))
+}
+
+// UK bank holidays observed by LSE. The holidays falling on a weekend are
+// moved to the next weekday.
+// -----------------------------------------------------------------------------
+func ukHolidays(year int) []time.Time {
+	holidays := []time.Time{
+		easter(year).AddDate(0, 0, -2),                 // Good Friday.
+		easter(year).AddDate(0, 0, 1),                  // Easter Monday.
+		nthWeekday(year, time.May, time.Monday, 1),     // Early May bank holiday.
+		nthWeekday(year, time.May, time.Monday, -1),    // Spring bank holiday.
+		nthWeekday(year, time.August, time.Monday, -1), // Summer bank holiday.
+	}
+	holidays = append(holidays, substitute(date(year, time.January, 1))...)
+	return append(holidays, substitute(date(year, time.December, 25), date(year, time.December, 26))...)
+}
+
+// -----------------------------------------------------------------------------
+func germanHolidays(year int) []time.Time {
+	return []time.Time{
+		date(year, time.January, 1),
+		easter(year).AddDate(0, 0, -2), // Good Friday.
+		easter(year).AddDate(0, 0, 1),  // Easter Monday.
+		date(year, time.May, 1),
+		date(year, time.December, 24),
+		date(year, time.December, 25),
+		date(year, time.December, 26),
+		date(year, time.December, 31),
+	}
+}
+
+// -----------------------------------------------------------------------------
+func euronextHolidays(year int) []time.Time {
+	return []time.Time{
+		date(year, time.January, 1),
+		easter(year).AddDate(0, 0, -2), // Good Friday.
+		easter(year).AddDate(0, 0, 1),  // Easter Monday.
+		date(year, time.May, 1),
+		date(year, time.December, 25),
+		date(year, time.December, 26),
+	}
+}
+
+// European exchanges close early on Christmas Eve and New Year's Eve.
+// -----------------------------------------------------------------------------
+func christmasHalfDays(year int) []time.Time {
+	return []time.Time{date(year, time.December, 24), date(year, time.December, 31)}
+}
+
+// Japanese national holidays plus the days TSE is closed at the turn of the
+// year. The holiday falling on Sunday is observed on the next weekday that
+// is not a holiday, and the day between two holidays is a holiday too. The
+// equinox days are calculated and are good till 2099.
+// -----------------------------------------------------------------------------
+func japanHolidays(year int) []time.Time {
+	offset := float64(year-1980)*0.242194 - float64((year-1980)/4)
+	national := []time.Time{
+		date(year, time.January, 1),
+		nthWeekday(year, time.January, time.Monday, 2),   // Coming of Age Day.
+		date(year, time.February, 11),                    // National Foundation Day.
+		date(year, time.February, 23),                    // Emperor's Birthday.
+		date(year, time.March, int(20.8431+offset)),      // Vernal Equinox Day.
+		date(year, time.April, 29),                       // Showa Day.
+		date(year, time.May, 3),                          // Constitution Memorial Day.
+		date(year, time.May, 4),                          // Greenery Day.
+		date(year, time.May, 5),                          // Children's Day.
+		nthWeekday(year, time.July, time.Monday, 3),      // Marine Day.
+		date(year, time.August, 11),                      // Mountain Day.
+		nthWeekday(year, time.September, time.Monday, 3), // Respect for the Aged Day.
+		date(year, time.September, int(23.2488+offset)),  // Autumnal Equinox Day.
+		nthWeekday(year, time.October, time.Monday, 2),   // Sports Day.
+		date(year, time.November, 3),                     // Culture Day.
+		date(year, time.November, 23),                    // Labor Thanksgiving Day.
+	}
+
+	holiday := make(map[time.Time]bool)
+	for _, day := range national {
+		holiday[day] = true
+	}
+	var extra []time.Time
+	for _, day := range national {
+		if day.Weekday() == time.Sunday {
+			next := day.AddDate(0, 0, 1)
+			for holiday[next] {
+				next = next.AddDate(0, 0, 1)
+			}
+			extra = append(extra, next)
+		}
+		if between := day.AddDate(0, 0, 1); !holiday[between] && holiday[between.AddDate(0, 0, 1)] && between.Weekday() != time.Sunday {
+			extra = append(extra, between)
+		}
+	}
+
+	return append(append(national, extra...),
+		date(year, time.January, 2), date(year, time.January, 3), date(year, time.December, 31))
+}
+
+// -----------------------------------------------------------------------------
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Returns true if the given day is one of the days returned by the calendar
+// function.
+// -----------------------------------------------------------------------------
+func contains(calendar func(year int) []time.Time, t time.Time) bool {
+	year, month, day := t.Date()
+	for _, holiday := range calendar(year) {
+		if holiday.Year() == year && holiday.Month() == month && holiday.Day() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Moves the holidays falling on a weekend to the following weekdays that are
+// not holidays already.
+// -----------------------------------------------------------------------------
+func substitute(days ...time.Time) []time.Time {
+	taken := make(map[time.Time]bool)
+	for _, day := range days {
+		taken[day] = true
+	}
+	result := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			for taken[day] || day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+				day = day.AddDate(0, 0, 1)
+			}
+			taken[day] = true
+		}
+		result = append(result, day)
+	}
+	return result
+}
+
+// Moves the holiday falling on a weekend to the nearest weekday.
+// -----------------------------------------------------------------------------
+func observed(day time.Time) time.Time {
+	switch day.Weekday() {
+	case time.Saturday:
+		return day.AddDate(0, 0, -1)
+	case time.Sunday:
+		return day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// Returns n-th weekday of the month, or the last one if n is negative.
+// -----------------------------------------------------------------------------
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		last := date(year, month+1, 0)
+		return last.AddDate(0, 0, -((int(last.Weekday()) - int(weekday) + 7) % 7))
+	}
+	first := date(year, month, 1)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+(n-1)*7)
+}
+
+// Returns Easter Sunday of the given year (anonymous Gregorian algorithm).
+// -----------------------------------------------------------------------------
+func easter(year int) time.Time {
+	a, b, c := year%19, year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+
+	return date(year, time.Month(month), day)
+}
+
+// Returns the ticker suffix that identifies the exchange, ex. `L` for BARC.L,
+// or blank string if there is none.
+// -----------------------------------------------------------------------------
+func suffix(ticker string) string {
+	if i := strings.LastIndex(ticker, `.`); i >= 0 {
+		return ticker[i+1:]
+	}
+	return ``
+}
+
+// Returns true if the ticker is the crypto pair like BTC-USD or ETH-EUR. The
+// U.S. share classes and preferred shares have shorter suffixes, ex. BRK-B
+// or BAC-PL.
+// -----------------------------------------------------------------------------
+func currencyPair(ticker string) bool {
+	i := strings.LastIndex(ticker, `-`)
+	return i > 0 && len(ticker)-i-1 >= 3
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+)
+
+// day parses the date in YYYY-MM-DD format.
+func day(t *testing.T, str string) time.Time {
+	parsed, err := time.Parse(`2006-01-02`, str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// at returns the given exchange local time in YYYY-MM-DD hh:mm format.
+func at(t *testing.T, exchange *Exchange, str string) time.Time {
+	parsed, err := time.ParseInLocation(`2006-01-02 15:04`, str, exchange.zone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestEaster(t *testing.T) {
+	tests := map[int]string{
+		2000: `2000-04-23`,
+		2019: `2019-04-21`,
+		2024: `2024-03-31`,
+		2025: `2025-04-20`,
+		2026: `2026-04-05`,
+		2027: `2027-03-28`,
+		2038: `2038-04-25`,
+	}
+	for year, want := range tests {
+		if got := easter(year); !got.Equal(day(t, want)) {
+			t.Errorf(`%d: got %s, want %s`, year, got.Format(`2006-01-02`), want)
+		}
+	}
+}
+
+func TestNthWeekday(t *testing.T) {
+	tests := []struct {
+		month   time.Month
+		weekday time.Weekday
+		n       int
+		want    string
+	}{
+		{time.January, time.Monday, 3, `2026-01-19`},    // Martin Luther King Jr. Day.
+		{time.May, time.Monday, -1, `2026-05-25`},       // Memorial Day.
+		{time.August, time.Monday, -1, `2026-08-31`},    // Summer bank holiday, the last day of the month.
+		{time.September, time.Monday, 1, `2026-09-07`},  // Labor Day.
+		{time.November, time.Thursday, 4, `2026-11-26`}, // Thanksgiving Day.
+		{time.December, time.Thursday, -1, `2026-12-31`},
+		{time.October, time.Thursday, 1, `2026-10-01`},
+	}
+	for _, test := range tests {
+		if got := nthWeekday(2026, test.month, test.weekday, test.n); !got.Equal(day(t, test.want)) {
+			t.Errorf(`%s %s #%d: got %s, want %s`, test.month, test.weekday, test.n, got.Format(`2006-01-02`), test.want)
+		}
+	}
+}
+
+func TestObserved(t *testing.T) {
+	tests := map[string]string{
+		`2026-07-04`: `2026-07-03`, // Saturday.
+		`2027-07-04`: `2027-07-05`, // Sunday.
+		`2025-07-04`: `2025-07-04`, // Friday.
+	}
+	for holiday, want := range tests {
+		if got := observed(day(t, holiday)); !got.Equal(day(t, want)) {
+			t.Errorf(`%s: got %s, want %s`, holiday, got.Format(`2006-01-02`), want)
+		}
+	}
+}
+
+func TestExchangeHolidays(t *testing.T) {
+	const (
+		trading = iota // Regular trading day.
+		closed         // Weekend or holiday.
+		halfDay        // Trading day with early close.
+	)
+	tests := []struct {
+		exchange *Exchange
+		day      string
+		want     int
+	}{
+		{nyse, `2026-01-01`, closed},
+		{nyse, `2026-01-19`, closed},  // Martin Luther King Jr. Day.
+		{nyse, `2026-02-16`, closed},  // Washington's Birthday.
+		{nyse, `2026-04-03`, closed},  // Good Friday.
+		{nyse, `2026-04-06`, trading}, // No Easter Monday.
+		{nyse, `2026-05-25`, closed},  // Memorial Day.
+		{nyse, `2026-06-19`, closed},  // Juneteenth.
+		{nyse, `2021-06-18`, trading}, // Not a holiday before 2022.
+		{nyse, `2022-06-20`, closed},  // Juneteenth on Sunday.
+		{nyse, `2025-07-03`, halfDay}, // Day before Independence Day.
+		{nyse, `2025-07-04`, closed},
+		{nyse, `2026-07-02`, trading}, // Independence Day is on Saturday, no half day before the observed one.
+		{nyse, `2026-07-03`, closed},
+		{nyse, `2027-07-02`, trading}, // Independence Day is on Sunday.
+		{nyse, `2027-07-05`, closed},
+		{nyse, `2026-09-07`, closed},  // Labor Day.
+		{nyse, `2026-11-26`, closed},  // Thanksgiving Day.
+		{nyse, `2026-11-27`, halfDay}, // The day after.
+		{nyse, `2026-12-24`, halfDay},
+		{nyse, `2026-12-25`, closed},
+		{nyse, `2021-12-24`, closed},  // Christmas is on Saturday.
+		{nyse, `2021-12-31`, trading}, // New Year's Day on Saturday isn't observed.
+		{nyse, `2023-01-02`, closed},  // New Year's Day is on Sunday.
+		{nyse, `2026-10-17`, closed},  // Saturday.
+
+		{lse, `2026-04-03`, closed}, // Good Friday.
+		{lse, `2026-04-06`, closed}, // Easter Monday.
+		{lse, `2026-05-04`, closed}, // Early May bank holiday.
+		{lse, `2026-05-25`, closed}, // Spring bank holiday.
+		{lse, `2026-08-31`, closed}, // Summer bank holiday.
+		{lse, `2026-12-24`, halfDay},
+		{lse, `2026-12-28`, closed}, // Boxing Day is on Saturday.
+		{lse, `2026-12-31`, halfDay},
+		{lse, `2021-12-27`, closed}, // Christmas is on Saturday.
+		{lse, `2021-12-28`, closed}, // Boxing Day is on Sunday.
+		{lse, `2021-12-29`, trading},
+		{lse, `2022-12-26`, closed}, // Christmas is on Sunday, Boxing Day is on Monday.
+		{lse, `2022-12-27`, closed},
+		{lse, `2022-01-03`, closed}, // New Year's Day is on Saturday.
+		{lse, `2026-07-03`, trading},
+
+		{xetra, `2026-04-03`, closed},
+		{xetra, `2026-04-06`, closed},
+		{xetra, `2026-05-01`, closed},
+		{xetra, `2026-05-25`, trading}, // Whit Monday.
+		{xetra, `2026-12-24`, closed},
+		{xetra, `2026-12-31`, closed},
+
+		{euronext, `2026-04-06`, closed},
+		{euronext, `2026-05-01`, closed},
+		{euronext, `2026-12-24`, halfDay},
+		{euronext, `2025-12-26`, closed},
+		{euronext, `2026-12-31`, halfDay},
+
+		{tse, `2026-01-02`, closed},  // Turn of the year.
+		{tse, `2026-01-05`, trading}, // First trading day of the year.
+		{tse, `2026-01-12`, closed},  // Coming of Age Day.
+		{tse, `2024-02-12`, closed},  // National Foundation Day is on Sunday.
+		{tse, `2025-03-20`, closed},  // Vernal Equinox Day.
+		{tse, `2026-03-20`, closed},  // Vernal Equinox Day.
+		{tse, `2027-03-22`, closed},  // Vernal Equinox Day is on Sunday (March 21).
+		{tse, `2026-05-06`, closed},  // Constitution Memorial Day is on Sunday, May 4 and 5 are holidays already.
+		{tse, `2026-05-07`, trading}, // Only one substitute day.
+		{tse, `2025-09-15`, closed},  // Respect for the Aged Day.
+		{tse, `2025-09-22`, trading}, // Not between two holidays.
+		{tse, `2025-09-23`, closed},  // Autumnal Equinox Day.
+		{tse, `2026-09-21`, closed},  // Respect for the Aged Day.
+		{tse, `2026-09-22`, closed},  // Between two holidays.
+		{tse, `2026-09-23`, closed},  // Autumnal Equinox Day.
+		{tse, `2026-09-24`, trading}, // Back to business.
+		{tse, `2026-11-23`, closed},  // Labor Thanksgiving Day.
+		{tse, `2026-12-30`, trading}, // Last trading day of the year.
+		{tse, `2026-12-31`, closed},  // Turn of the year.
+	}
+	for _, test := range tests {
+		local := at(t, test.exchange, test.day+` 12:00`)
+		got := trading
+		if !test.exchange.IsTradingDay(local) {
+			got = closed
+		} else if test.exchange.IsHalfDay(local) {
+			got = halfDay
+		}
+		if got != test.want {
+			t.Errorf(`%s %s %s: got %v, want %v`, test.exchange.Name, test.day, local.Weekday(), got, test.want)
+		}
+	}
+}
+
+func TestExchangeSession(t *testing.T) {
+	tests := []struct {
+		exchange *Exchange
+		time     string // Exchange local time.
+		session  string
+		next     string // Start of the next session, exchange local time.
+	}{
+		{nyse, `2026-10-19 03:59`, SessionClosed, `2026-10-19 04:00`},
+		{nyse, `2026-10-19 04:00`, SessionPre, `2026-10-20 04:00`},
+		{nyse, `2026-10-19 09:30`, SessionRegular, `2026-10-20 04:00`},
+		{nyse, `2026-10-19 16:00`, SessionPost, `2026-10-20 04:00`},
+		{nyse, `2026-10-19 20:00`, SessionClosed, `2026-10-20 04:00`},
+		{nyse, `2026-11-27 12:59`, SessionRegular, `2026-11-30 04:00`}, // Half day.
+		{nyse, `2026-11-27 13:00`, SessionPost, `2026-11-30 04:00`},
+		{nyse, `2026-11-27 17:00`, SessionClosed, `2026-11-30 04:00`},
+		{nyse, `2026-04-02 21:00`, SessionClosed, `2026-04-06 04:00`}, // Good Friday and the weekend.
+		{nyse, `2026-04-03 10:00`, SessionClosed, `2026-04-06 04:00`},
+		{nyse, `2026-12-31 22:00`, SessionClosed, `2027-01-04 04:00`},  // New Year's Day and the weekend.
+		{nyse, `2026-03-09 09:30`, SessionRegular, `2026-03-10 04:00`}, // Daylight saving time.
+
+		{lse, `2021-12-24 12:29`, SessionRegular, `2021-12-29 08:00`}, // Half day before the Christmas substitute days.
+		{lse, `2021-12-24 12:30`, SessionClosed, `2021-12-29 08:00`},
+		{lse, `2026-10-19 16:29`, SessionRegular, `2026-10-20 08:00`},
+
+		{euronext, `2026-12-24 14:04`, SessionRegular, `2026-12-28 09:00`},
+		{euronext, `2026-12-24 14:05`, SessionClosed, `2026-12-28 09:00`},
+
+		{tse, `2026-10-19 11:29`, SessionRegular, `2026-10-20 09:00`},
+		{tse, `2026-10-19 11:30`, SessionBreak, `2026-10-20 09:00`},
+		{tse, `2026-10-19 12:30`, SessionRegular, `2026-10-20 09:00`},
+		{tse, `2026-10-19 15:30`, SessionClosed, `2026-10-20 09:00`},
+		{tse, `2026-09-18 16:00`, SessionClosed, `2026-09-24 09:00`}, // Silver week.
+		{tse, `2026-12-30 16:00`, SessionClosed, `2027-01-04 09:00`}, // Turn of the year.
+	}
+	for _, test := range tests {
+		now := at(t, test.exchange, test.time)
+		if got := test.exchange.Session(now); got != test.session {
+			t.Errorf(`%s %s: got %s session, want %s`, test.exchange.Name, test.time, got, test.session)
+		}
+		if got := test.exchange.NextSession(now); !got.Equal(at(t, test.exchange, test.next)) {
+			t.Errorf(`%s %s: got next session at %s, want %s`, test.exchange.Name, test.time, got.In(test.exchange.zone()).Format(`2006-01-02 15:04`), test.next)
+		}
+		// The session doesn't depend on the time zone of the given time.
+		if got := test.exchange.Session(now.UTC()); got != test.session {
+			t.Errorf(`%s %s UTC: got %s session, want %s`, test.exchange.Name, test.time, got, test.session)
+		}
+	}
+}
+
+func TestExchangeFor(t *testing.T) {
+	tests := map[string]*Exchange{
+		`AAPL`:      nyse,
+		`BRK-B`:     nyse,
+		`BAC-PL`:    nyse,
+		`BARC.L`:    lse,
+		`SAP.DE`:    xetra,
+		`ASML.AS`:   euronext,
+		`7203.T`:    tse,
+		`^GSPC`:     nyse,
+		`^N225`:     tse,
+		`BTC-USD`:   nil,
+		`ETH-USDT`:  nil,
+		`EURUSD=X`:  nil,
+		`GC=F`:      nil,
+		`005930.KS`: nil,
+		`^VIX`:      nil,
+	}
+	for ticker, want := range tests {
+		if got := ExchangeFor(ticker); got != want {
+			t.Errorf(`%s: got %v, want %v`, ticker, got, want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Chart ranges in the order they are shown in the chart view, along with
+// their titles and time axis label formats.
+var chartRanges = []struct {
+	span   string
+	title  string
+	layout string
+}{
+	{Range1D, `1D`, `3:04pm`},
+	{Range5D, `5D`, `Jan 2`},
+	{Range1M, `1M`, `Jan 2`},
+	{Range6M, `6M`, `Jan 2`},
+	{Range1Y, `1Y`, `Jan 2`},
+	{Range5Y, `5Y`, `Jan 2006`},
+}
+
+// Number of screen rows taken by the volume bars.
+const volumeRows = 3
+
+// Braille dot bits indexed by dot row and column within the character cell.
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// ChartView takes over the screen to draw price and volume chart of a single
+// stock. When activated it draws intraday chart, then waits for arrow or
+// number keys (choose another range), or Esc (exit).
+type ChartView struct {
+	C      chan bool // Charts fetched in the background, to be drawn.
+	screen *Screen   // Pointer to Screen to draw the chart.
+	charts *Charts   // Price history cache, nil if the provider has none.
+	ticker string    // Stock ticker.
+	span   int       // Index of the chart range in chartRanges.
+}
+
+// Returns new ChartView for the given stock ticker and draws the chart.
+func NewChartView(screen *Screen, quotes *Quotes, ticker string) *ChartView {
+	view := &ChartView{
+		C:      make(chan bool, 1),
+		screen: screen,
+		charts: quotes.charts,
+		ticker: ticker,
+	}
+
+	return view.Draw()
+}
+
+// Handle takes over the keyboard events to pick the chart range. It returns
+// true when user presses Esc or q.
+func (view *ChartView) Handle(event termbox.Event) bool {
+	switch {
+	case event.Key == termbox.KeyEsc || event.Ch == 'q' || event.Ch == 'Q':
+		return true
+	case event.Key == termbox.KeyArrowLeft || event.Ch == 'h':
+		view.span = (view.span + len(chartRanges) - 1) % len(chartRanges)
+	case event.Key == termbox.KeyArrowRight || event.Ch == 'l':
+		view.span = (view.span + 1) % len(chartRanges)
+	case event.Ch >= '1' && event.Ch < '1'+rune(len(chartRanges)):
+		view.span = int(event.Ch - '1')
+	default:
+		return false
+	}
+	view.Draw()
+
+	return false
+}
+
+// Draw draws the chart using the whole screen. The chart that hasn't been
+// fetched recently gets fetched in the background: meanwhile the chart
+// fetched before, if any, is shown as loading, and once the chart arrives
+// it's sent to the C channel so that the main loop draws it again.
+func (view *ChartView) Draw() *ChartView {
+	var chart *Chart
+	err := errNoCharts
+	loading := false
+	if view.charts != nil {
+		span := chartRanges[view.span].span
+		if chart, loading, err = view.charts.lookup(view.ticker, span); loading {
+			go view.fetch(view.ticker, span)
+		}
+	}
+
+	width, height := termbox.Size()
+	lines := []string{view.title(chart, loading), ``}
+	switch {
+	case chart == nil && loading:
+		lines = append(lines, `Loading `+view.ticker+`…`)
+	case chart == nil:
+		lines = append(lines, `<loss>Error: `+err.Error()+`</>`)
+	case len(chart.Close) == 0:
+		lines = append(lines, `No price history for `+view.ticker)
+	case width < 40 || height < 12:
+		lines = append(lines, `The screen is too small to draw the chart`)
+	default:
+		lines = append(lines, view.plot(chart, width, height-volumeRows-5)...)
+		lines = append(lines, view.volume(chart, width)...)
+		lines = append(lines, view.axis(chart, width))
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, ``)
+	}
+	lines = append(lines, `<tag>←/→ 1-6</> change range  <tag>Esc q</> back to quotes`)
+
+	view.screen.Clear().Draw(strings.Join(lines, "\n"))
+
+	return view
+}
+
+// Fetches the chart and lets the main loop know it's there to be drawn. One
+// pending notification is enough since Draw picks up all the fetched charts.
+// -----------------------------------------------------------------------------
+func (view *ChartView) fetch(ticker, span string) {
+	view.charts.Fetch(ticker, span)
+	select {
+	case view.C <- true:
+	default:
+	}
+}
+
+// Formats the line with the ticker, chart ranges, and the latest price and
+// change since the previous close.
+// -----------------------------------------------------------------------------
+func (view *ChartView) title(chart *Chart, loading bool) string {
+	str := `<tag>` + view.ticker + `</> `
+	for i, span := range chartRanges {
+		if i == view.span {
+			str += `<r> ` + span.title + ` </r>`
+		} else {
+			str += ` ` + span.title + ` `
+		}
+	}
+	if loading && chart != nil {
+		str += ` loading…`
+	}
+	if chart == nil || len(chart.Close) == 0 {
+		return str
+	}
+
+	last := Float(chart.Close[len(chart.Close)-1])
+	str += `<right>` + currency(last, chart.Currency)
+	if chart.PreviousClose.Valid && chart.PreviousClose.Value != 0 {
+		change := Float(last.Value - chart.PreviousClose.Value)
+		changePct := Float(change.Value / chart.PreviousClose.Value * 100)
+		str += ` ` + colorize(currency(change, chart.Currency)+` (`+percent(changePct, ``)+`)`, colorFor(change)) +
+			` <tag>prev close</> ` + currency(chart.PreviousClose, chart.Currency)
+	}
+
+	return str + `</right>`
+}
+
+// Draws the price line and the dotted previous close line with braille
+// characters, and labels the price axis.
+// -----------------------------------------------------------------------------
+func (view *ChartView) plot(chart *Chart, width, rows int) []string {
+	columns := width - 11
+	low, high := chart.Close[0], chart.Close[0]
+	for _, value := range chart.Close {
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
+	if chart.PreviousClose.Valid {
+		low, high = math.Min(low, chart.PreviousClose.Value), math.Max(high, chart.PreviousClose.Value)
+	}
+	if high == low {
+		low, high = low-1, high+1
+	}
+
+	// Each character cell has 2x4 dots. The kind of the cell tells whether
+	// it has the price line (1) or the previous close line (2) so that they
+	// could be drawn in different colors.
+	dots := make([][]rune, rows)
+	kind := make([][]int, rows)
+	for row := range dots {
+		dots[row] = make([]rune, columns)
+		kind[row] = make([]int, columns)
+	}
+	y := func(value float64) int {
+		return int((high-value)/(high-low)*float64(rows*4-1) + 0.5)
+	}
+	set := func(x, y, k int) {
+		dots[y/4][x/2] |= brailleDots[y%4][x%2]
+		if kind[y/4][x/2] != 1 {
+			kind[y/4][x/2] = k
+		}
+	}
+
+	if chart.PreviousClose.Valid {
+		for x := 0; x < columns*2; x += 3 {
+			set(x, y(chart.PreviousClose.Value), 2)
+		}
+	}
+	previous := y(chart.Close[0])
+	for x := 0; x < columns*2; x++ {
+		current := y(chart.Close[x*len(chart.Close)/(columns*2)])
+		from, to := previous, current
+		if from > to {
+			from, to = to, from
+		}
+		for dot := from; dot <= to; dot++ {
+			set(x, dot, 1)
+		}
+		previous = current
+	}
+
+	color := colorFor(Float(chart.Close[len(chart.Close)-1] - chart.PreviousClose.Value))
+	if !chart.PreviousClose.Valid {
+		color = colorFor(Float(chart.Close[len(chart.Close)-1] - chart.Close[0]))
+	}
+	colors := []string{``, color, `tag`}
+
+	lines := make([]string, rows)
+	for row := range lines {
+		label := ``
+		switch row {
+		case 0:
+			label = fmt.Sprintf(`%.2f`, high)
+		case rows / 2:
+			label = fmt.Sprintf(`%.2f`, (high+low)/2)
+		case rows - 1:
+			label = fmt.Sprintf(`%.2f`, low)
+		}
+		str := fmt.Sprintf(`%10s `, label)
+		for column := range dots[row] {
+			char := ' '
+			if dots[row][column] != 0 {
+				char = 0x2800 + dots[row][column]
+			}
+			str += colorize(string(char), colors[kind[row][column]])
+		}
+		lines[row] = str
+	}
+
+	return lines
+}
+
+// Draws the volume bars with block characters scaled to the highest volume.
+// -----------------------------------------------------------------------------
+func (view *ChartView) volume(chart *Chart, width int) []string {
+	columns := width - 11
+	volumes := make([]int64, columns)
+	if len(chart.Volume) >= columns {
+		for i, volume := range chart.Volume {
+			volumes[i*columns/len(chart.Volume)] += volume.Value
+		}
+	} else {
+		for column := range volumes {
+			volumes[column] = chart.Volume[column*len(chart.Volume)/columns].Value
+		}
+	}
+	highest := int64(0)
+	for _, volume := range volumes {
+		if volume > highest {
+			highest = volume
+		}
+	}
+
+	blocks := []rune(` ▁▂▃▄▅▆▇█`)
+	lines := make([]string, volumeRows)
+	for row := range lines {
+		label := ``
+		if row == 0 && highest > 0 {
+			label = integer(Int(highest), ``)
+		}
+		str := fmt.Sprintf(`%10s `, label)
+		for _, volume := range volumes {
+			eighths := 0
+			if highest > 0 {
+				eighths = int(volume*volumeRows*8/highest) - (volumeRows-1-row)*8
+			}
+			if eighths < 0 {
+				eighths = 0
+			} else if eighths > 8 {
+				eighths = 8
+			}
+			str += string(blocks[eighths])
+		}
+		lines[row] = `<tag>` + str + `</>`
+	}
+
+	return lines
+}
+
+// Labels the time axis every 16 characters.
+// -----------------------------------------------------------------------------
+func (view *ChartView) axis(chart *Chart, width int) string {
+	columns := width - 11
+	str := strings.Repeat(` `, 11)
+	for column := 0; column+12 <= columns; column += 16 {
+		at := chart.Times[column*len(chart.Times)/columns]
+		str += fmt.Sprintf(`%-16s`, `|`+at.Format(chartRanges[view.span].layout))
+	}
+
+	return str
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Chart ranges supported by the chart providers.
+const (
+	Range1D = `1d`
+	Range5D = `5d`
+	Range1M = `1mo`
+	Range6M = `6mo`
+	Range1Y = `1y`
+	Range5Y = `5y`
+)
+
+// Interval between the data points for each chart range.
+var chartIntervals = map[string]string{
+	Range1D: `5m`,
+	Range5D: `15m`,
+	Range1M: `60m`,
+	Range6M: `1d`,
+	Range1Y: `1d`,
+	Range5Y: `1wk`,
+}
+
+// Number of data points shown in the Sparkline column.
+const sparklineWidth = 10
+
+// Chart is price and volume history of the stock over the chart range.
+type Chart struct {
+	Ticker        string      `json:"ticker"`        // Stock ticker.
+	Range         string      `json:"range"`         // Chart range, ex. 1d.
+	Currency      string      `json:"currency"`      // String code for currency of stock.
+	PreviousClose NullFloat   `json:"previousClose"` // Close price before the start of the range.
+	Times         []time.Time `json:"times"`         // Time of each data point.
+	Close         []float64   `json:"close"`         // Close price of each data point.
+	Volume        []NullInt   `json:"volume"`        // Trading volume of each data point.
+}
+
+// ChartProvider is implemented by data providers that can fetch price and
+// volume history.
+type ChartProvider interface {
+	FetchChart(ticker, span string) (*Chart, error)
+}
+
+// Number of charts fetched at the same time by FetchAll.
+const chartFetchers = 4
+
+// Charts caches the charts fetched from the provider so that they get
+// requested at most once per refresh interval regardless of how often the
+// stock quotes get fetched.
+type Charts struct {
+	sync.Mutex
+	provider ChartProvider            // Provider to fetch the charts.
+	refresh  time.Duration            // How long the fetched charts stay fresh.
+	cache    map[string]*chartItem    // Fetched charts keyed by range and ticker.
+	pending  map[string]chan struct{} // Charts being fetched, closed when done.
+}
+
+// chartItem is the chart or the error returned by the provider.
+type chartItem struct {
+	chart   *Chart    // Fetched chart, nil on error.
+	err     error     // Error, if any.
+	fetched time.Time // When the chart was fetched.
+}
+
+// Returns new Charts that fetch the charts from the given provider, or nil
+// if the provider doesn't support the charts.
+func NewCharts(provider StockProvider, refresh time.Duration) *Charts {
+	chartProvider, ok := provider.(ChartProvider)
+	if !ok {
+		return nil
+	}
+	return &Charts{
+		provider: chartProvider,
+		refresh:  refresh,
+		cache:    make(map[string]*chartItem),
+		pending:  make(map[string]chan struct{}),
+	}
+}
+
+// Fetch returns the chart of the given ticker over the given range. The
+// chart gets fetched from the provider unless it has been fetched within
+// the refresh interval. Concurrent requests for the same chart wait for
+// the one being fetched instead of fetching it again.
+func (charts *Charts) Fetch(ticker, span string) (*Chart, error) {
+	key := span + `:` + ticker
+
+	charts.Lock()
+	if item, ok := charts.cache[key]; ok && time.Since(item.fetched) < charts.refresh {
+		charts.Unlock()
+		return item.chart, item.err
+	}
+	if done, ok := charts.pending[key]; ok {
+		charts.Unlock()
+		<-done
+		charts.Lock()
+		item := charts.cache[key]
+		charts.Unlock()
+		return item.chart, item.err
+	}
+	done := make(chan struct{})
+	charts.pending[key] = done
+	charts.Unlock()
+
+	chart, err := charts.provider.FetchChart(ticker, span)
+
+	charts.Lock()
+	if err != nil {
+		if item, ok := charts.cache[key]; ok && item.chart != nil {
+			chart = item.chart // Keep showing the chart fetched before.
+		}
+	}
+	charts.cache[key] = &chartItem{chart: chart, err: err, fetched: time.Now()}
+	delete(charts.pending, key)
+	charts.Unlock()
+	close(done)
+
+	return chart, err
+}
+
+// FetchAll fetches the charts of the given tickers over the given range
+// unless they have been fetched within the refresh interval. Up to
+// chartFetchers charts are fetched at the same time, and the errors are
+// cached along with the charts.
+func (charts *Charts) FetchAll(tickers []string, span string) {
+	var wait sync.WaitGroup
+	fetchers := make(chan struct{}, chartFetchers)
+	for _, ticker := range tickers {
+		wait.Add(1)
+		fetchers <- struct{}{}
+		go func(ticker string) {
+			defer wait.Done()
+			charts.Fetch(ticker, span)
+			<-fetchers
+		}(ticker)
+	}
+	wait.Wait()
+}
+
+// Cached returns the chart fetched before, fresh or not, without fetching
+// it. It returns nil if the chart hasn't been fetched yet.
+func (charts *Charts) Cached(ticker, span string) *Chart {
+	charts.Lock()
+	defer charts.Unlock()
+
+	if item, ok := charts.cache[span+`:`+ticker]; ok {
+		return item.chart
+	}
+	return nil
+}
+
+// Returns the cached chart or the error, and whether the chart needs to be
+// fetched because it's not there or has expired.
+// -----------------------------------------------------------------------------
+func (charts *Charts) lookup(ticker, span string) (*Chart, bool, error) {
+	charts.Lock()
+	defer charts.Unlock()
+
+	item, ok := charts.cache[span+`:`+ticker]
+	if !ok {
+		return nil, true, nil
+	}
+	return item.chart, time.Since(item.fetched) >= charts.refresh, item.err
+}
+
+// Sparkline returns close prices of the chart resampled to the given number
+// of points, or nil if the chart has no data.
+func (chart *Chart) Sparkline(points int) []float64 {
+	if chart == nil || len(chart.Close) == 0 {
+		return nil
+	}
+	if len(chart.Close) <= points {
+		return append([]float64(nil), chart.Close...)
+	}
+
+	// Pick the last close price in each bucket so that the sparkline ends
+	// with the latest price.
+	sparkline := make([]float64, points)
+	for i := range sparkline {
+		sparkline[i] = chart.Close[(i+1)*len(chart.Close)/points-1]
+	}
+	return sparkline
+}
+
+// errNoCharts is returned by the providers that wrap other providers that
+// don't support the charts.
+var errNoCharts = errors.New(`charts are not supported by the data provider`)
@@ -0,0 +1,77 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// chartCounter is the chart provider that counts the requests per ticker
+// and how many of them run at the same time.
+type chartCounter struct {
+	sync.Mutex
+	calls   map[string]int
+	running int
+	peak    int
+}
+
+func (counter *chartCounter) FetchChart(ticker, span string) (*Chart, error) {
+	counter.Lock()
+	counter.calls[ticker]++
+	counter.running++
+	if counter.running > counter.peak {
+		counter.peak = counter.running
+	}
+	counter.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	counter.Lock()
+	counter.running--
+	counter.Unlock()
+	return &Chart{Ticker: ticker, Range: span, Close: []float64{1, 2}}, nil
+}
+
+func TestChartsFetchAll(t *testing.T) {
+	counter := &chartCounter{calls: make(map[string]int)}
+	charts := &Charts{
+		provider: counter,
+		refresh:  time.Minute,
+		cache:    make(map[string]*chartItem),
+		pending:  make(map[string]chan struct{}),
+	}
+	tickers := []string{`AAPL`, `MSFT`, `GOOG`, `AMZN`, `META`, `NVDA`, `TSLA`, `AAPL`, `MSFT`}
+
+	var wait sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			charts.FetchAll(tickers, Range1D)
+		}()
+	}
+	wait.Wait()
+	charts.FetchAll(tickers, Range1D) // All the charts are fresh by now.
+
+	for ticker, calls := range counter.calls {
+		if calls != 1 {
+			t.Errorf(`%s chart fetched %d times, want once`, ticker, calls)
+		}
+	}
+	if len(counter.calls) != 7 {
+		t.Errorf(`got %d charts fetched, want 7`, len(counter.calls))
+	}
+	if counter.peak > 3*chartFetchers {
+		t.Errorf(`got %d charts fetched at the same time, want at most %d`, counter.peak, 3*chartFetchers)
+	}
+	if chart := charts.Cached(`NVDA`, Range1D); chart == nil || chart.Ticker != `NVDA` {
+		t.Errorf(`got cached chart %v, want NVDA`, chart)
+	}
+	if chart := charts.Cached(`NVDA`, Range5D); chart != nil {
+		t.Errorf(`got cached chart %v for range that hasn't been fetched`, chart)
+	}
+}
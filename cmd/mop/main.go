@@ -7,10 +7,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/user"
 	"path"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/eiannone/keyboard"
@@ -28,18 +31,34 @@ NO WARRANTIES OF ANY KIND WHATSOEVER. SEE THE LICENSE FILE FOR DETAILS.
 <u>Command</u>    <u>Description                                </u>
    +                  Add stocks to list
    -                  Remove stocks from list
+   $                  Set position (shares and cost) in a stock
+   Tab ] [            Switch to next/previous watchlist
+   1-9                Switch to watchlist by number
+   n                  Create new watchlist
+   X                  Delete current watchlist
+   A                  Add alert (or -name to remove one)
+   c                  Show price chart of a stock
    ? h H              Display this help screen
    f                  Set filtering expression
    F                  Unset filtering expression
-   g G                Group stocks by advancing/declining issues
-   o                  Change column sort order
+   s                  Apply saved filter, +name to save current one, -name to remove, name>new to rename
+   g G                Group stocks: advancing/declining, market, exchange, currency, sector, tag, watchlist
+   z Z                Collapse/expand group of the selected stock, all groups
+   o                  Sort, move, resize, hide, and show columns
    p P                Pause market data and stock updates
    t                  Toggle timestamp on/off
+   e                  Toggle extended hours: off, row, replace
+   Enter              Show details of the selected stock
+   x                  Remove the selected stock
+   a                  Add alert on the selected stock
+   N                  Write note about the selected stock
+   #                  Set tags and @alias of the selected stock
    Mouse Scroll       Scroll up/down
+   Mouse Click        Select stock
    PgUp/PgDn          Scroll up/down
-   Up/Down arrows     Scroll up
-   j J                Scroll up
-   k K                Scroll down
+   Up/Down arrows     Move cursor up/down
+   j k                Move cursor down/up
+   J K                Scroll down/up
    q esc              Quit mop
 
 Enter comma-delimited list of stock tickers when prompted.
@@ -48,9 +67,11 @@ Enter comma-delimited list of stock tickers when prompted.
 `
 
 // -----------------------------------------------------------------------------
-func mainLoop(screen *mop.Screen, profile *mop.Profile) {
+func mainLoop(screen *mop.Screen, profile *mop.Profile, provider mop.StockProvider, refresh time.Duration) {
 	var lineEditor *mop.LineEditor
 	var columnEditor *mop.ColumnEditor
+	var chartView *mop.ChartView
+	var detailView *mop.DetailView
 
 	termbox.SetInputMode(termbox.InputMouse)
 	termbox.SetOutputMode(termbox.Output256)
@@ -59,8 +80,14 @@ func mainLoop(screen *mop.Screen, profile *mop.Profile) {
 	keyboardQueue := make(chan termbox.Event, 128)
 
 	timestampQueue := time.NewTicker(1 * time.Second)
-	quotesQueue := time.NewTicker(time.Duration(profile.QuotesRefresh) * time.Second)
-	marketQueue := time.NewTicker(time.Duration(profile.MarketRefresh) * time.Second)
+	quotesRefresh := time.Duration(profile.QuotesRefresh) * time.Second
+	marketRefresh := time.Duration(profile.MarketRefresh) * time.Second
+	closedRefresh := time.Duration(profile.ClosedRefresh) * time.Second
+	if refresh > 0 {
+		quotesRefresh, marketRefresh, closedRefresh = refresh, refresh, 0
+	}
+	quotesQueue := time.NewTicker(quotesRefresh)
+	marketQueue := time.NewTicker(marketRefresh)
 	showingHelp := false
 	paused := false
 	showingTimestamp := profile.ShowTimestamp
@@ -80,40 +107,87 @@ func mainLoop(screen *mop.Screen, profile *mop.Profile) {
 		}
 	}()
 
-	provider := mop.NewYahooProvider()
-	market := mop.NewMarket(provider)
+	market := mop.NewMarket(profile, provider)
 	quotes := mop.NewQuotes(market, profile, provider)
 	quotesResultQueue := make(chan *mop.Quotes)
+	flash := mop.NewFlashNotifier()
+	quotes.Alerts().AddNotifier(`flash`, flash)
 	marketResultQueue := make(chan *mop.Market)
 
 	market = market.Fetch()
 	quotes = quotes.Fetch()
+	quotes.Alerts().Save()
 	screen.Draw(market)
 	screen.Draw(quotes)
+	if closedRefresh != 0 {
+		marketQueue.Reset(market.Refresh(marketRefresh, closedRefresh))
+		quotesQueue.Reset(quotes.Refresh(quotesRefresh, closedRefresh))
+	}
 
 loop:
 	for {
+		var chartQueue chan bool
+		if chartView != nil {
+			chartQueue = chartView.C
+		}
+
 		select {
 		case event := <-keyboardQueue:
 			switch event.Type {
 			case termbox.EventKey:
-				if lineEditor == nil && columnEditor == nil && !showingHelp {
+				if lineEditor == nil && columnEditor == nil && chartView == nil && detailView == nil && !showingHelp {
 					if event.Key == termbox.KeyEsc || event.Ch == 'q' || event.Ch == 'Q' {
 						break loop
 					} else if event.Ch == '+' || event.Ch == '-' {
 						lineEditor = mop.NewLineEditor(screen, quotes)
 						lineEditor.Prompt(event.Ch)
-					} else if event.Ch == 'f' {
+					} else if event.Ch == 'f' || event.Ch == '$' || event.Ch == 'n' || event.Ch == 'X' || event.Ch == 'A' || event.Ch == 'c' || event.Ch == 's' {
 						lineEditor = mop.NewLineEditor(screen, quotes)
 						lineEditor.Prompt(event.Ch)
+					} else if event.Ch == 'x' || event.Ch == 'a' || event.Ch == 'N' || event.Ch == '#' {
+						if stock := screen.Selected(); stock != nil {
+							lineEditor = mop.NewLineEditor(screen, quotes).For(stock.Ticker)
+							lineEditor.Prompt(event.Ch)
+						}
+					} else if event.Key == termbox.KeyEnter {
+						if stock := screen.Selected(); stock != nil {
+							detailView = mop.NewDetailView(screen, quotes, stock.Ticker)
+						}
 					} else if event.Ch == 'F' {
 						profile.SetFilter("")
 						redrawQuotesFlag = true
 					} else if event.Ch == 'o' || event.Ch == 'O' {
 						columnEditor = mop.NewColumnEditor(screen, quotes)
+					} else if event.Key == termbox.KeyTab || event.Ch == ']' || event.Ch == '[' || (event.Ch >= '1' && event.Ch <= '9') {
+						var err error
+						if event.Ch == '[' {
+							err = quotes.NextWatchlist(-1)
+						} else if event.Ch >= '1' && event.Ch <= '9' {
+							err = quotes.SelectWatchlist(int(event.Ch - '1'))
+						} else {
+							err = quotes.NextWatchlist(1)
+						}
+						if err == nil {
+							screen.ScrollTop()
+							screen.Clear().Draw(market, quotes)
+						}
 					} else if event.Ch == 'g' || event.Ch == 'G' {
-						if profile.Regroup() == nil {
-							redrawQuotesFlag = true
+						step := 1
+						if event.Ch == 'G' {
+							step = -1
+						}
+						if profile.Regroup(step) == nil {
+							screen.Clear().Draw(market, quotes)
+						}
+					} else if event.Ch == 'z' {
+						if stock := screen.Selected(); stock != nil && profile.GroupOf(stock) != `` {
+							if profile.ToggleGroup(profile.GroupOf(stock)) == nil {
+								screen.Clear().Draw(market, quotes)
+							}
+						}
+					} else if event.Ch == 'Z' {
+						if quotes.ToggleGroups() == nil {
+							screen.Clear().Draw(market, quotes)
 						}
 					} else if event.Ch == 'p' || event.Ch == 'P' {
 						paused = !paused
@@ -130,10 +204,10 @@ loop:
 						screen.DecreaseOffset(upDownJump)
 						redrawQuotesFlag = true
 					} else if event.Key == termbox.KeyArrowUp || event.Ch == 'k' {
-						screen.DecreaseOffset(1)
+						screen.MoveCursor(-1)
 						redrawQuotesFlag = true
 					} else if event.Key == termbox.KeyArrowDown || event.Ch == 'j' {
-						screen.IncreaseOffset(1)
+						screen.MoveCursor(1)
 						redrawQuotesFlag = true
 					} else if event.Key == termbox.KeyHome {
 						screen.ScrollTop()
@@ -146,22 +220,44 @@ loop:
 							showingTimestamp = !showingTimestamp
 							screen.Clear().Draw(market, quotes)
 						}
+					} else if event.Ch == 'e' || event.Ch == 'E' {
+						if profile.ToggleExtendedHours() == nil {
+							screen.Clear().Draw(market, quotes)
+						}
 					}
 				} else if lineEditor != nil {
 					if done := lineEditor.Handle(event); done {
+						chartView = lineEditor.Chart()
 						lineEditor = nil
 					}
 				} else if columnEditor != nil {
 					if done := columnEditor.Handle(event); done {
 						columnEditor = nil
 					}
+				} else if chartView != nil {
+					if done := chartView.Handle(event); done {
+						chartView = nil
+						screen.Clear().Draw(market, quotes)
+					}
+				} else if detailView != nil {
+					if done := detailView.Handle(event); done {
+						chartView = detailView.Chart()
+						detailView = nil
+						if chartView == nil {
+							screen.Clear().Draw(market, quotes)
+						}
+					}
 				} else if showingHelp {
 					showingHelp = false
 					screen.Clear().Draw(market, quotes)
 				}
 			case termbox.EventResize:
 				screen.Resize()
-				if !showingHelp {
+				if chartView != nil {
+					chartView.Draw()
+				} else if detailView != nil {
+					detailView.Draw()
+				} else if !showingHelp {
 					// screen.Draw(market)
 					// redrawQuotesFlag = true
 					// screen.Draw(market)
@@ -172,7 +268,7 @@ loop:
 					screen.Draw(help)
 				}
 			case termbox.EventMouse:
-				if lineEditor == nil && columnEditor == nil && !showingHelp {
+				if lineEditor == nil && columnEditor == nil && chartView == nil && detailView == nil && !showingHelp {
 					switch event.Key {
 					case termbox.MouseWheelUp:
 						screen.DecreaseOffset(5)
@@ -180,12 +276,20 @@ loop:
 					case termbox.MouseWheelDown:
 						screen.IncreaseOffset(5)
 						redrawQuotesFlag = true
+					case termbox.MouseLeft:
+						if name := screen.GroupAt(event.MouseY); name != `` {
+							if profile.ToggleGroup(name) == nil {
+								screen.Clear().Draw(market, quotes)
+							}
+						} else if screen.SelectRow(event.MouseY) {
+							redrawQuotesFlag = true
+						}
 					}
 				}
 			}
 
 		case <-timestampQueue.C:
-			if !showingHelp && !paused && showingTimestamp {
+			if !showingHelp && !paused && showingTimestamp && chartView == nil && detailView == nil {
 				screen.Draw(time.Now())
 			}
 
@@ -197,10 +301,22 @@ loop:
 			}
 
 		case q := <-quotesResultQueue:
+			q.Alerts().Save() // The alerts state gets saved here since the profile is only changed by the main loop.
 			if !showingHelp && !paused && len(keyboardQueue) == 0 {
 				quotes = q
 				redrawQuotesFlag = true
 			}
+			if closedRefresh != 0 {
+				quotesQueue.Reset(q.Refresh(quotesRefresh, closedRefresh))
+			}
+
+		case <-chartQueue:
+			chartView.Draw()
+
+		case <-flash.C:
+			if !showingHelp {
+				screen.Flash()
+			}
 
 		case <-marketQueue.C:
 			if !showingHelp && !paused {
@@ -214,8 +330,22 @@ loop:
 				market = m
 				redrawMarketFlag = true
 			}
+			if closedRefresh != 0 {
+				marketQueue.Reset(m.Refresh(marketRefresh, closedRefresh))
+			}
 		}
 
+		if chartView != nil || detailView != nil {
+			if redrawQuotesFlag && len(keyboardQueue) == 0 {
+				// The chart or the details get updated along with the quotes.
+				if chartView != nil {
+					chartView.Draw()
+				} else {
+					detailView.Draw()
+				}
+			}
+			redrawQuotesFlag, redrawMarketFlag = false, false
+		}
 		if redrawQuotesFlag && len(keyboardQueue) == 0 {
 			screen.DrawOldQuotes(quotes)
 			redrawQuotesFlag = false
@@ -227,6 +357,99 @@ loop:
 	}
 }
 
+// -----------------------------------------------------------------------------
+func importLedger(profile *mop.Profile, files []string, name string) error {
+	mapping, err := mop.LookupMapping(name, profile.Mappings)
+	if err != nil {
+		return err
+	}
+	ledger, err := mop.LoadLedger(profile.LedgerFilename())
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		reader, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		added, skipped, err := ledger.Import(reader, mapping)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		fmt.Printf("%s: %d transactions imported, %d already in the ledger\n", file, added, skipped)
+	}
+	if err := ledger.Save(); err != nil {
+		return err
+	}
+	if err := profile.SyncHoldings(ledger); err != nil {
+		return err
+	}
+
+	positions := ledger.Positions()
+	tickers := make([]string, 0, len(positions))
+	for ticker := range positions {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "Ticker\tShares\tAvg Cost\tRealized\tDividends\t")
+	for _, ticker := range tickers {
+		position := positions[ticker]
+		avgCost := 0.0
+		if holding := position.Holding(); holding != nil {
+			avgCost = holding.Cost
+		}
+		fmt.Fprintf(writer, "%s\t%.4g\t%.2f\t%.2f\t%.2f\t\n", ticker, position.Shares(), avgCost, position.Realized, position.Dividends)
+	}
+
+	return writer.Flush()
+}
+
+// -----------------------------------------------------------------------------
+func printOnce(profile *mop.Profile, provider mop.StockProvider, format string) error {
+	market := mop.NewMarket(profile, provider).Fetch()
+	quotes := mop.NewQuotes(market, profile, provider)
+	quotes.Alerts().Mute() // Printing the quotes must not fire the notifiers or change the profile.
+	quotes.Fetch()
+	if err := mop.NewLayout().Print(os.Stdout, format, market, quotes); err != nil {
+		return err
+	}
+
+	var errors []string
+	if ok, err := market.Ok(); !ok {
+		errors = append(errors, err)
+	}
+	if ok, err := quotes.Ok(); !ok {
+		errors = append(errors, err)
+	}
+	if err := profile.ComputedErrors(); err != `` {
+		errors = append(errors, err)
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, ` | `))
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+func runServer(profile *mop.Profile, provider mop.StockProvider, refresh time.Duration, listen string) error {
+	quotesRefresh := time.Duration(profile.QuotesRefresh) * time.Second
+	marketRefresh := time.Duration(profile.MarketRefresh) * time.Second
+	closedRefresh := time.Duration(profile.ClosedRefresh) * time.Second
+	if refresh > 0 {
+		quotesRefresh, marketRefresh, closedRefresh = refresh, refresh, 0
+	}
+
+	server := mop.NewServer(profile, provider)
+	go server.Run(marketRefresh, quotesRefresh, closedRefresh)
+
+	fmt.Fprintf(os.Stderr, "Serving market data and stock quotes on http://%s/api/\n", listen)
+	return http.ListenAndServe(listen, server)
+}
+
 // -----------------------------------------------------------------------------
 func main() {
 	usr, err := user.Current()
@@ -235,15 +458,65 @@ func main() {
 		os.Exit(1)
 	}
 
+	// `mop serve` runs the HTTP server instead of the interactive mode.
+	serve := len(os.Args) > 1 && os.Args[1] == "serve"
+	if serve {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	profileName := flag.String("profile", path.Join(usr.HomeDir, defaultProfile), "path to profile")
+	replay := flag.String("replay", "", "comma-separated list of recorded Yahoo JSON files, directories, or globs to replay instead of fetching live data")
+	replayInterval := flag.Duration("replay-interval", 0, "time between replayed snapshots (default: next snapshot on every quotes refresh)")
+	record := flag.String("record", "", "record all market data and stock quotes to the given session file")
+	playback := flag.String("playback", "", "play back the session file written with -record")
+	speed := flag.Float64("speed", 1.0, "session playback speed (2 plays back twice as fast as recorded)")
+	skip := flag.Duration("skip", 0, "start session playback this far into the recorded session")
+	importFiles := flag.String("import", "", "comma-separated list of broker CSV exports to import into the transaction ledger")
+	mapping := flag.String("mapping", "mop", "column mapping of the imported CSV files: mop, schwab, fidelity, vanguard, or custom one from the profile")
+	once := flag.Bool("once", false, "fetch market data and stock quotes once, print them to stdout, and exit")
+	format := flag.String("format", mop.FormatTable, "output format of -once: table, json, or csv")
+	listen := flag.String("listen", "127.0.0.1:8080", "address `mop serve` listens on")
 	flag.Parse()
 
+	if *once && *format != mop.FormatTable && *format != mop.FormatJSON && *format != mop.FormatCSV {
+		fmt.Fprintf(os.Stderr, "Unknown output format `%s`\n", *format)
+		os.Exit(2)
+	}
+
+	var provider mop.StockProvider = mop.NewYahooProvider()
+	var refresh time.Duration
+	if *replay != "" {
+		provider, err = mop.NewReplayProvider(strings.Split(*replay, ","), *replayInterval)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading replay files: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *playback != "" {
+		provider, err = mop.NewPlaybackProvider(*playback, *speed, *skip)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading session: %v\n", err)
+			os.Exit(1)
+		}
+		refresh = time.Second // Pick up recorded updates as they come.
+	}
+	if *record != "" {
+		recorder, err := mop.NewRecordingProvider(provider, *record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating session file: %v\n", err)
+			os.Exit(1)
+		}
+		defer recorder.Close()
+		provider = recorder
+	}
+
 	profile, err := mop.NewProfile(*profileName)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "The profile read from `%s` is corrupted.\n\tError: %s\n\n", *profileName, err)
+		if *once {
+			os.Exit(1)
+		}
 
 		// Loop until we get a "y" or "n" answer.
-		// Note: This is only for the interactive mode. Once we have the "one-shot", this should be skipped
 		for {
 			fmt.Fprintln(os.Stderr, "Do you want to overwrite the current profile with the default one? [y/n]")
 			rne, _, _ := keyboard.GetSingleKey()
@@ -261,6 +534,28 @@ func main() {
 			}
 		}
 	}
+	if *importFiles != "" {
+		if err := importLedger(profile, strings.Split(*importFiles, ","), *mapping); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing transactions: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if serve {
+		if err := runServer(profile, provider, refresh, *listen); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *once {
+		if err := printOnce(profile, provider, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	screen, err := mop.NewScreen(profile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing screen: %v\n", err)
@@ -268,6 +563,6 @@ func main() {
 	}
 	defer screen.Close()
 
-	mainLoop(screen, profile)
+	mainLoop(screen, profile, provider, refresh)
 	profile.Save()
 }
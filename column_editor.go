@@ -6,14 +6,17 @@ package mop
 
 import "github.com/nsf/termbox-go"
 
-// ColumnEditor handles column sort order. When activated it highlights
-// current column name in the header, then waits for arrow keys (choose
-// another column), Enter (reverse sort order), or Esc (exit).
+// ColumnEditor handles column sort order and the set of columns shown. When
+// activated it highlights current column name in the header, then waits for
+// arrow keys (choose another column), Enter (reverse sort order), s (sort
+// by the column next), < and > (move the column), ( and ) (make it narrower
+// or wider), - (hide it), + (show hidden column), or Esc (exit).
 type ColumnEditor struct {
-	screen  *Screen  // Pointer to Screen so we could use screen.Draw().
-	quotes  *Quotes  // Pointer to Quotes to redraw them when the sort order changes.
-	layout  *Layout  // Pointer to Layout to redraw stock quotes header.
-	profile *Profile // Pointer to Profile where we save newly selected sort order.
+	screen  *Screen     // Pointer to Screen so we could use screen.Draw().
+	quotes  *Quotes     // Pointer to Quotes to redraw them when the sort order changes.
+	layout  *Layout     // Pointer to Layout to redraw stock quotes header.
+	profile *Profile    // Pointer to Profile where we save newly selected sort order.
+	editor  *LineEditor // Line editor prompting for the hidden column to show, if any.
 }
 
 // Returns new initialized ColumnEditor struct. As part of initialization it
@@ -34,20 +37,60 @@ func NewColumnEditor(screen *Screen, quotes *Quotes) *ColumnEditor {
 // Handle takes over the keyboard events and dispatches them to appropriate
 // column editor handlers. It returns true when user presses Esc.
 func (editor *ColumnEditor) Handle(event termbox.Event) bool {
+	if editor.editor != nil {
+		if done := editor.editor.Handle(event); done {
+			if !editor.editor.hasError {
+				editor.redraw()
+			}
+			editor.editor = nil
+		}
+		return false
+	}
 	defer editor.redrawHeader()
 
-	switch event.Key {
-	case termbox.KeyEsc:
+	switch {
+	case event.Key == termbox.KeyEsc:
 		return editor.done()
 
-	case termbox.KeyEnter:
+	case event.Key == termbox.KeyEnter:
 		editor.execute()
 
-	case termbox.KeyArrowLeft:
+	case event.Key == termbox.KeyArrowLeft:
 		editor.selectLeftColumn()
 
-	case termbox.KeyArrowRight:
+	case event.Key == termbox.KeyArrowRight:
 		editor.selectRightColumn()
+
+	case event.Ch == 's':
+		editor.update(editor.profile.ThenSortBy(editor.layout.sortKeys(editor.profile), editor.selectedName()))
+
+	case event.Ch == '<' || event.Ch == '>':
+		step := 1
+		if event.Ch == '<' {
+			step = -1
+		}
+		editor.update(editor.layout.MoveColumn(editor.profile, editor.selectedName(), step))
+
+	case event.Ch == '(' || event.Ch == ')':
+		delta := 1
+		if event.Ch == '(' {
+			delta = -1
+		}
+		editor.update(editor.layout.ResizeColumn(editor.profile, editor.selectedName(), delta))
+
+	case event.Ch == '-':
+		name := editor.selectedName()
+		editor.selectRightColumn() // Select the next column once this one is hidden.
+		if editor.layout.ShowColumn(editor.profile, name, false) == nil {
+			editor.redraw()
+		} else {
+			editor.profile.selectedColumn = editor.layout.index(editor.profile, name)
+		}
+
+	case event.Ch == '+':
+		if len(editor.layout.Hidden(editor.profile)) > 0 {
+			editor.editor = NewLineEditor(editor.screen, editor.quotes).Prompt('o')
+		}
 	}
 
 	return false
@@ -56,45 +99,91 @@ func (editor *ColumnEditor) Handle(event termbox.Event) bool {
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectCurrentColumn() *ColumnEditor {
 	editor.profile.selectedColumn = editor.profile.SortColumn
+	if columns := editor.layout.catalog(editor.profile); editor.profile.SortColumn >= len(columns) ||
+		editor.profile.Hidden(columns[editor.profile.SortColumn].name) {
+		editor.profile.selectedColumn = 0 // The sort column is hidden, start with Ticker.
+	}
+	editor.redrawHelp()
 	editor.redrawHeader()
 	return editor
 }
 
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectLeftColumn() *ColumnEditor {
-	editor.profile.selectedColumn--
-	if editor.profile.selectedColumn < 0 {
-		editor.profile.selectedColumn = editor.layout.TotalColumns() - 1
-	}
-	return editor
+	return editor.selectColumn(-1)
 }
 
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) selectRightColumn() *ColumnEditor {
-	editor.profile.selectedColumn++
-	if editor.profile.selectedColumn > editor.layout.TotalColumns()-1 {
-		editor.profile.selectedColumn = 0
+	return editor.selectColumn(1)
+}
+
+// Selects the visible column that is step columns away from the selected
+// one, wrapping around the ends of the header.
+// -----------------------------------------------------------------------------
+func (editor *ColumnEditor) selectColumn(step int) *ColumnEditor {
+	columns := editor.layout.visible(editor.profile)
+	current := -1
+	for i, column := range columns {
+		if editor.layout.index(editor.profile, column.name) == editor.profile.selectedColumn {
+			current = i
+		}
+	}
+	if current < 0 {
+		current, step = 0, 0 // The selected column is no longer visible.
 	}
+	current = (current + step + len(columns)) % len(columns)
+	editor.profile.selectedColumn = editor.layout.index(editor.profile, columns[current].name)
 	return editor
 }
 
+// -----------------------------------------------------------------------------
+func (editor *ColumnEditor) selectedName() string {
+	return editor.layout.catalog(editor.profile)[editor.profile.selectedColumn].name
+}
+
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) execute() *ColumnEditor {
-	if editor.profile.Reorder() == nil {
+	if editor.profile.Reorder(editor.selectedName()) == nil {
 		editor.screen.Draw(editor.quotes)
 	}
 
 	return editor
 }
 
+// -----------------------------------------------------------------------------
+func (editor *ColumnEditor) update(err error) *ColumnEditor {
+	if err == nil {
+		editor.redraw()
+	}
+
+	return editor
+}
+
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) done() bool {
 	editor.profile.selectedColumn = -1
+	editor.screen.ClearLine(0, editor.screen.PromptLine())
 	return true
 }
 
+// Redraws the stock quotes since the columns have changed. The screen gets
+// cleared first so that narrower rows don't leave the old text behind.
+// -----------------------------------------------------------------------------
+func (editor *ColumnEditor) redraw() {
+	editor.screen.Clear().Draw(editor.quotes.market, editor.quotes)
+	editor.redrawHelp()
+}
+
+// -----------------------------------------------------------------------------
+func (editor *ColumnEditor) redrawHelp() {
+	editor.screen.ClearLine(0, editor.screen.PromptLine())
+	editor.screen.DrawLine(0, editor.screen.PromptLine(),
+		`<white>←/→ select  Enter sort  s then by  < > move  ( ) width  - hide  + show  Esc done</>`)
+}
+
 // -----------------------------------------------------------------------------
 func (editor *ColumnEditor) redrawHeader() {
-	editor.screen.DrawLine(0, 4, editor.layout.Header(editor.profile))
+	editor.screen.DrawLine(0, editor.screen.headerLine, editor.layout.Header(editor.profile))
 	termbox.Flush()
 }
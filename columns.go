@@ -0,0 +1,252 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import "fmt"
+
+// ColumnConfig is the user's choice of the stock quotes column position,
+// width, and visibility. The columns are shown in the order they are listed
+// in the profile; the columns that are not listed get shown after them.
+type ColumnConfig struct {
+	Name   string // The name of the field in the Stock struct, ex. LastTrade.
+	Width  int    `json:",omitempty"` // Column width, the default one if zero.
+	Hidden bool   `json:",omitempty"` // True when the column is not shown.
+}
+
+// Limits of the column width set by the user.
+const (
+	minColumnWidth = 3
+	maxColumnWidth = 40
+)
+
+// Columns returns all the stock quotes columns in the order they are shown
+// along with their width and visibility. Unlike Profile.Columns the list
+// includes every column known to the layout as well as computed columns.
+// The optional columns showing Yahoo quote fields and the Sparkline column
+// are hidden unless listed. The widths edited by hand are kept within the
+// limits.
+func (layout *Layout) Columns(profile *Profile) []ColumnConfig {
+	var configs []ColumnConfig
+	listed := make(map[string]bool)
+	for _, config := range profile.Columns {
+		if column := layout.column(profile, config.Name); column != nil && !listed[config.Name] {
+			switch {
+			case config.Width == 0:
+				config.Width = abs(column.width)
+			case config.Width < minColumnWidth:
+				config.Width = minColumnWidth
+			case config.Width > maxColumnWidth:
+				config.Width = maxColumnWidth
+			}
+			configs = append(configs, config)
+			listed[config.Name] = true
+		}
+	}
+	for _, column := range layout.catalog(profile) {
+		if !listed[column.name] {
+			configs = append(configs, ColumnConfig{Name: column.name, Width: abs(column.width), Hidden: hiddenColumn(column.name)})
+		}
+	}
+	return configs
+}
+
+// visible returns the columns that are shown, in the order they are shown
+// and with the widths set by the user.
+func (layout *Layout) visible(profile *Profile) []Column {
+	var columns []Column
+	for _, config := range layout.Columns(profile) {
+		if !config.Hidden {
+			column := *layout.column(profile, config.Name)
+			if column.width < 0 {
+				column.width = -config.Width // Left aligned.
+			} else {
+				column.width = config.Width
+			}
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// catalog returns the columns known to the layout followed by the optional
+// columns showing Yahoo quote fields and annotations, and the computed
+// columns defined in the profile. The column numbers used by SortColumn
+// refer to this list.
+func (layout *Layout) catalog(profile *Profile) []Column {
+	columns := make([]Column, 0, len(layout.columns)+len(quoteFields)+len(annotationColumns)+len(profile.ComputedColumns))
+	columns = append(columns, layout.columns...)
+	for _, field := range quoteFields {
+		columns = append(columns, field.column())
+	}
+	columns = append(columns, annotationColumns...)
+	for _, computed := range profile.ComputedColumns {
+		columns = append(columns, computed.column())
+	}
+	return columns
+}
+
+// column returns the column with the given name, or nil if there is none.
+func (layout *Layout) column(profile *Profile, name string) *Column {
+	if i := layout.index(profile, name); i >= 0 {
+		return &layout.catalog(profile)[i]
+	}
+	return nil
+}
+
+// index returns the number of the column with the given name as used by
+// Profile.SortColumn, or -1 if there is no such column.
+func (layout *Layout) index(profile *Profile, name string) int {
+	for i, column := range layout.catalog(profile) {
+		if column.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetColumns saves the position, width, and visibility of the columns.
+func (profile *Profile) SetColumns(columns []ColumnConfig) error {
+	profile.Columns = columns
+	return profile.Save()
+}
+
+// Hidden returns true if the user has hidden the column with the given name,
+// or hasn't shown the column that is hidden by default.
+func (profile *Profile) Hidden(name string) bool {
+	for _, config := range profile.Columns {
+		if config.Name == name {
+			return config.Hidden
+		}
+	}
+	return hiddenColumn(name)
+}
+
+// MoveColumn moves the column with the given name past the next visible
+// column on the right, or on the left if step is negative.
+func (layout *Layout) MoveColumn(profile *Profile, name string, step int) error {
+	configs := layout.Columns(profile)
+	from := position(configs, name)
+	to := from + step
+	for to >= 0 && to < len(configs) && configs[to].Hidden {
+		to += step
+	}
+	if from < 0 || to < 0 || to >= len(configs) {
+		return nil // Nowhere to move.
+	}
+	config := configs[from]
+	configs = append(configs[:from], configs[from+1:]...)
+	configs = append(configs[:to], append([]ColumnConfig{config}, configs[to:]...)...)
+
+	return layout.save(profile, configs)
+}
+
+// ResizeColumn makes the column with the given name wider by delta, or
+// narrower if delta is negative.
+func (layout *Layout) ResizeColumn(profile *Profile, name string, delta int) error {
+	configs := layout.Columns(profile)
+	i := position(configs, name)
+	if i < 0 {
+		return nil
+	}
+	width := configs[i].Width + delta
+	if width < minColumnWidth || width > maxColumnWidth {
+		return nil
+	}
+	configs[i].Width = width
+
+	return layout.save(profile, configs)
+}
+
+// ShowColumn shows or hides the column with the given name. The Ticker
+// column and the last visible column can't be hidden.
+func (layout *Layout) ShowColumn(profile *Profile, name string, show bool) error {
+	if !show && (name == `Ticker` || len(layout.visible(profile)) < 2) {
+		return fmt.Errorf("column `%s` can't be hidden", name)
+	}
+	configs := layout.Columns(profile)
+	i := position(configs, name)
+	if i < 0 {
+		return fmt.Errorf("no column `%s`", name)
+	}
+	configs[i].Hidden = !show
+
+	return layout.save(profile, configs)
+}
+
+// Hidden returns the columns that are not shown.
+func (layout *Layout) Hidden(profile *Profile) []Column {
+	var columns []Column
+	for _, config := range layout.Columns(profile) {
+		if config.Hidden {
+			columns = append(columns, *layout.column(profile, config.Name))
+		}
+	}
+	return columns
+}
+
+// Saves the columns in the profile leaving out the default widths so that
+// they follow the layout, and the columns hidden by default that are not
+// shown.
+// -----------------------------------------------------------------------------
+func (layout *Layout) save(profile *Profile, configs []ColumnConfig) error {
+	var saved []ColumnConfig
+	for _, config := range configs {
+		if config.Width == abs(layout.column(profile, config.Name).width) {
+			config.Width = 0
+		}
+		if !config.Hidden || config.Width != 0 || !hiddenColumn(config.Name) {
+			saved = append(saved, config)
+		}
+	}
+	return profile.SetColumns(saved)
+}
+
+// Returns true if the column is not shown unless the user chooses to show
+// it: the optional columns, and the Sparkline column that takes a chart
+// request per stock.
+// -----------------------------------------------------------------------------
+func hiddenColumn(name string) bool {
+	return name == `Sparkline` || optionalColumn(name)
+}
+
+// -----------------------------------------------------------------------------
+func position(configs []ColumnConfig, name string) int {
+	for i, config := range configs {
+		if config.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the color of the row as per the direction of the stock price.
+// -----------------------------------------------------------------------------
+func rowColor(stock *Stock) string {
+	return colorFor(Float(float64(stock.Direction)))
+}
+
+// Returns the function that picks the column color as per the sign of the
+// given Stock field.
+// -----------------------------------------------------------------------------
+func signColor(name string) func(*Stock) string {
+	return func(stock *Stock) string {
+		value, ok := number(stock.value(name))
+		return colorFor(NullFloat{Value: value, Valid: ok})
+	}
+}
+
+// Returns the color of the Sparkline column as per the intraday trend.
+// -----------------------------------------------------------------------------
+func trendColor(stock *Stock) string {
+	return colorFor(stock.trend())
+}
+
+// -----------------------------------------------------------------------------
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Formats of the computed columns along with the functions to format them.
+var computedFormats = map[string]func(interface{}, string) string{
+	`number`:   blank,
+	`currency`: currency,
+	`percent`:  percent,
+	`integer`:  integer,
+}
+
+// ComputedColumn is the column defined by the user as an expression over
+// the same variables as the filter, ex. `volume / avgVolume`. The value of
+// the expression gets calculated for every stock whenever the quotes get
+// arranged for display.
+type ComputedColumn struct {
+	Name       string                         // Column name used in Columns and for sorting.
+	Title      string                         `json:",omitempty"` // Column title, defaults to the name.
+	Expression string                         // Numeric expression in human form.
+	Width      int                            `json:",omitempty"` // Column width, defaults to 10.
+	Format     string                         `json:",omitempty"` // number (the default), currency, percent, or integer.
+	Color      bool                           `json:",omitempty"` // True to show positive values as gains and negative ones as losses.
+	expression *govaluate.EvaluableExpression // The expression as a govaluate expression.
+	err        error                          // Error, if any, while compiling the expression.
+}
+
+// column returns the layout column that shows the computed values.
+func (computed *ComputedColumn) column() Column {
+	column := Column{width: 10, name: computed.Name, title: computed.Name, formatter: blank}
+	if computed.Width > 0 {
+		column.width = computed.Width
+	}
+	if computed.Title != `` {
+		column.title = computed.Title
+	}
+	if formatter, ok := computedFormats[computed.Format]; ok {
+		column.formatter = formatter
+	}
+	if computed.Color {
+		column.color = signColor(computed.Name)
+	}
+	return column
+}
+
+// compute calculates the values of the computed columns for the given
+// stocks. The values that can't be calculated, ex. due to division by zero,
+// are not available.
+func compute(stocks []Stock, profile *Profile) {
+	if len(profile.ComputedColumns) == 0 {
+		return
+	}
+	for i := range stocks {
+		values := filterValues(&stocks[i])
+		stocks[i].Computed = make(map[string]NullFloat, len(profile.ComputedColumns))
+		for _, computed := range profile.ComputedColumns {
+			stocks[i].Computed[computed.Name] = computed.evaluate(values)
+		}
+	}
+}
+
+// ComputedErrors returns the errors of the computed columns that can't be
+// calculated, or blank string if there are none.
+func (profile *Profile) ComputedErrors() string {
+	str := ``
+	for _, computed := range profile.ComputedColumns {
+		if computed.compile() != nil {
+			if str != `` {
+				str += ` | `
+			}
+			str += fmt.Sprintf("column `%s`: %s", computed.Name, computed.err)
+		}
+	}
+	return str
+}
+
+// -----------------------------------------------------------------------------
+func (computed *ComputedColumn) compile() error {
+	if computed.expression != nil || computed.err != nil {
+		return computed.err
+	}
+	if computed.Name == `` {
+		computed.err = fmt.Errorf("no column name")
+	} else if reservedColumn(computed.Name) {
+		computed.err = fmt.Errorf("the name is taken by another column")
+	} else if expr, err := compileExpression(computed.Expression); err != nil {
+		computed.err = err
+	} else if err := validateFilter(expr); err != nil {
+		computed.err = err
+	} else {
+		computed.expression = expr
+	}
+	return computed.err
+}
+
+// -----------------------------------------------------------------------------
+func (computed *ComputedColumn) evaluate(values map[string]interface{}) NullFloat {
+	if computed.compile() != nil {
+		return NullFloat{}
+	}
+	result, err := computed.expression.Evaluate(values)
+	if err != nil {
+		return NullFloat{}
+	}
+	switch result := result.(type) {
+	case float64:
+		if !math.IsNaN(result) && !math.IsInf(result, 0) {
+			return Float(result)
+		}
+	case bool:
+		if result {
+			return Float(1)
+		}
+		return Float(0)
+	}
+	return NullFloat{}
+}
+
+// Returns true if the name is a field of the Stock struct or the Yahoo
+// quote and therefore can't be used as the name of the computed column.
+// -----------------------------------------------------------------------------
+func reservedColumn(name string) bool {
+	_, ok := reflect.TypeOf(Stock{}).FieldByName(name)
+	return ok || optionalColumn(name)
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Key statistics shown at the top of the details along with the functions
+// to format them given stock currency. The fields are looked up in the data
+// returned by Yahoo.
+var detailFields = []struct {
+	key       string
+	title     string
+	formatter func(interface{}, string) string
+}{
+	{`longName`, `Name`, nil},
+	{`fullExchangeName`, `Exchange`, nil},
+	{`quoteType`, `Type`, nil},
+	{`currency`, `Currency`, nil},
+	{`regularMarketPreviousClose`, `Previous close`, currency},
+	{`regularMarketDayRange`, `Day range`, nil},
+	{`fiftyTwoWeekRange`, `52-week range`, nil},
+	{`fiftyDayAverage`, `50-day average`, currency},
+	{`twoHundredDayAverage`, `200-day average`, currency},
+	{`averageDailyVolume3Month`, `Avg volume (3M)`, integer},
+	{`marketCap`, `Market cap`, currency},
+	{`sharesOutstanding`, `Shares outstanding`, integer},
+	{`trailingPE`, `P/E`, blank},
+	{`forwardPE`, `Forward P/E`, blank},
+	{`priceToBook`, `Price/book`, blank},
+	{`bookValue`, `Book value`, currency},
+	{`epsTrailingTwelveMonths`, `EPS (TTM)`, currency},
+	{`epsForward`, `EPS (forward)`, currency},
+	{`trailingAnnualDividendRate`, `Dividend`, currency},
+	{`trailingAnnualDividendYield`, `Dividend yield`, fraction},
+	{`dividendDate`, `Dividend date`, timestamp},
+	{`earningsTimestamp`, `Earnings date`, timestamp},
+}
+
+// DetailView takes over the screen to show every field the data provider
+// returns for a single stock along with the user's note. While the details
+// are shown the stock can be charted, alerted on, annotated, or removed.
+type DetailView struct {
+	screen *Screen     // Pointer to Screen to draw the details.
+	quotes *Quotes     // Pointer to Quotes to look up the latest stock quote.
+	ticker string      // Stock ticker.
+	offset int         // Number of rows scrolled past.
+	editor *LineEditor // Line editor prompting for the action, if any.
+	chart  *ChartView  // Chart view opened from the details, if any.
+}
+
+// Returns new DetailView for the given stock ticker and draws the details.
+func NewDetailView(screen *Screen, quotes *Quotes, ticker string) *DetailView {
+	view := &DetailView{
+		screen: screen,
+		quotes: quotes,
+		ticker: ticker,
+	}
+
+	return view.Draw()
+}
+
+// Handle takes over the keyboard events to scroll the details and to run
+// the stock actions. It returns true when user presses Esc or q, opens the
+// chart, or removes the stock.
+func (view *DetailView) Handle(event termbox.Event) bool {
+	if view.editor != nil {
+		if done := view.editor.Handle(event); done {
+			hasError := view.editor.hasError
+			view.editor = nil
+			if !view.listed() {
+				return true // The stock has been removed.
+			}
+			if !hasError {
+				view.Draw()
+			}
+		}
+		return false
+	}
+
+	_, height := termbox.Size()
+	switch {
+	case event.Key == termbox.KeyEsc || event.Key == termbox.KeyEnter || event.Ch == 'q' || event.Ch == 'Q':
+		return true
+	case event.Ch == 'c' || event.Ch == 'C':
+		view.chart = NewChartView(view.screen, view.quotes, view.ticker)
+		return true
+	case event.Ch == 'x' || event.Ch == 'a' || event.Ch == 'n' || event.Ch == 'N' || event.Ch == '#':
+		command := event.Ch
+		if command == 'n' {
+			command = 'N'
+		}
+		view.editor = NewLineEditor(view.screen, view.quotes).For(view.ticker).At(height - 1).Prompt(command)
+		return false
+	case event.Key == termbox.KeyArrowUp || event.Ch == 'k':
+		view.offset--
+	case event.Key == termbox.KeyArrowDown || event.Ch == 'j':
+		view.offset++
+	case event.Key == termbox.KeyPgup || event.Ch == 'K':
+		view.offset -= height / 2
+	case event.Key == termbox.KeyPgdn || event.Ch == 'J':
+		view.offset += height / 2
+	case event.Key == termbox.KeyHome:
+		view.offset = 0
+	default:
+		return false
+	}
+	view.Draw()
+
+	return false
+}
+
+// Chart returns the chart view opened from the details, or nil if there is
+// none. The chart view takes over the keyboard once the details are closed.
+func (view *DetailView) Chart() *ChartView {
+	return view.chart
+}
+
+// Draw shows the latest quote and the fields of the stock using the whole
+// screen. Nothing gets drawn while the line editor is prompting for input.
+func (view *DetailView) Draw() *DetailView {
+	if view.editor != nil {
+		return view
+	}
+
+	width, height := termbox.Size()
+	stock := view.stock()
+	lines := []string{view.title(stock)}
+	if stock != nil {
+		lines = append(lines, view.quote(stock))
+	}
+	if annotation := view.quotes.profile.Annotation(view.ticker); annotation != nil {
+		if annotation.Alias != `` || len(annotation.Tags) > 0 {
+			lines = append(lines, `<tag>Alias</> `+annotation.Alias+`  <tag>Tags</> `+strings.Join(annotation.Tags, `, `))
+		}
+		if annotation.Note != `` {
+			lines = append(lines, `<tag>Note</> `+annotation.Note)
+		}
+	}
+	lines = append(lines, ``)
+
+	var body []string
+	if stock == nil || len(stock.Fields) == 0 {
+		body = append(body, `No details for `+view.ticker)
+	} else {
+		body = append(body, view.statistics(stock, width)...)
+		body = append(body, ``, `<u>All fields</u>`)
+		body = append(body, view.fields(stock, width)...)
+	}
+
+	rows := height - len(lines) - 1
+	if view.offset > len(body)-rows {
+		view.offset = len(body) - rows
+	}
+	if view.offset < 0 {
+		view.offset = 0
+	}
+	for i := view.offset; i < len(body) && i < view.offset+rows; i++ {
+		lines = append(lines, body[i])
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, ``)
+	}
+	lines = append(lines, `<tag>↑/↓</> scroll  <tag>c</> chart  <tag>a</> alert  <tag>n</> note  <tag>#</> tags  <tag>x</> remove  <tag>Esc q</> back to quotes`)
+
+	view.screen.Clear().Draw(strings.Join(lines, "\n"))
+
+	return view
+}
+
+// Returns the latest quote of the stock, or nil if it hasn't been fetched.
+// -----------------------------------------------------------------------------
+func (view *DetailView) stock() *Stock {
+	for _, stock := range view.quotes.stocks {
+		if stock.Ticker == view.ticker {
+			return &stock
+		}
+	}
+	return nil
+}
+
+// Returns true if the stock is still on the watchlist.
+// -----------------------------------------------------------------------------
+func (view *DetailView) listed() bool {
+	for _, ticker := range view.quotes.profile.Tickers {
+		if ticker == view.ticker {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats the line with the ticker, company name, and exchange.
+// -----------------------------------------------------------------------------
+func (view *DetailView) title(stock *Stock) string {
+	str := `<tag>` + view.ticker + `</>`
+	if stock == nil {
+		return str
+	}
+	if name := stringField(stock.Fields, `longName`); name != `` {
+		str += ` ` + name
+	} else if name := stringField(stock.Fields, `shortName`); name != `` {
+		str += ` ` + name
+	}
+	if exchange := stringField(stock.Fields, `fullExchangeName`); exchange != `` {
+		str += `<right>` + exchange + ` ` + stock.Currency + `</right>`
+	}
+	return str
+}
+
+// Formats the line with the last trade, change, volume, trading session,
+// and the pre-market or after hours trade if there is one.
+// -----------------------------------------------------------------------------
+func (view *DetailView) quote(stock *Stock) string {
+	str := fmt.Sprintf(`<tag>Last</> %s <tag>Change</> %s <tag>Volume</> %s`,
+		currency(stock.LastTrade, stock.Currency),
+		colorize(currency(stock.Change, stock.Currency)+` (`+percent(stock.ChangePct, ``)+`)`, colorFor(stock.Change)),
+		integer(stock.Volume, ``),
+	)
+	if stock.Session != `` {
+		str += ` <tag>Session</> ` + stock.Session
+	}
+	if !stock.Time.IsZero() {
+		str += ` <tag>at</> ` + stock.Time.Format(`Jan 2 3:04pm`)
+	}
+	if price, change, changePct, at, ok := stock.extended(); ok {
+		str += fmt.Sprintf(`  <tag>%s</> %s %s`, stock.Session, currency(price, stock.Currency),
+			colorize(currency(change, stock.Currency)+` (`+percent(changePct, ``)+`)`, colorFor(change)))
+		if !at.IsZero() {
+			str += ` <tag>at</> ` + at.Format(`3:04pm`)
+		}
+	}
+	return str
+}
+
+// Formats the key statistics that the data provider has returned.
+// -----------------------------------------------------------------------------
+func (view *DetailView) statistics(stock *Stock, width int) []string {
+	var entries []string
+	for _, field := range detailFields {
+		value, ok := stock.Fields[field.key]
+		if !ok {
+			continue
+		}
+		str := fmt.Sprint(value)
+		if field.formatter != nil {
+			str = field.formatter(value, stock.Currency)
+		}
+		entries = append(entries, entry(field.title, str, 20, 20))
+	}
+	return columnize(entries, width, 40)
+}
+
+// Formats all the fields that the data provider has returned sorted by name.
+// -----------------------------------------------------------------------------
+func (view *DetailView) fields(stock *Stock, width int) []string {
+	keys := make([]string, 0, len(stock.Fields))
+	for key := range stock.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = entry(key, rawField(key, stock.Fields[key]), 34, 24)
+	}
+	return columnize(entries, width, 58)
+}
+
+// Formats the title and the value padded or truncated to the given widths.
+// -----------------------------------------------------------------------------
+func entry(title, value string, titleWidth, valueWidth int) string {
+	if runes := []rune(value); len(runes) > valueWidth-1 {
+		value = string(runes[:valueWidth-2]) + `…`
+	}
+	return fmt.Sprintf(`<tag>%-*s</>%-*s`, titleWidth, title, valueWidth, value)
+}
+
+// Lays out the entries of the given width in as many columns as fit on the
+// screen. The entries go left to right, then top to bottom.
+// -----------------------------------------------------------------------------
+func columnize(entries []string, width, entryWidth int) []string {
+	columns := width / entryWidth
+	if columns < 1 {
+		columns = 1
+	}
+	var lines []string
+	for i, entry := range entries {
+		if i%columns == 0 {
+			lines = append(lines, ``)
+		}
+		lines[len(lines)-1] += entry
+	}
+	return lines
+}
+
+// Formats the raw field value as returned by the data provider. Timestamps
+// are shown as dates, and lists and objects as JSON.
+// -----------------------------------------------------------------------------
+func rawField(key string, value interface{}) string {
+	switch value := value.(type) {
+	case float64:
+		if strings.HasSuffix(key, `Milliseconds`) && strings.Contains(key, `Date`) {
+			return time.Unix(int64(value)/1000, 0).Format(`Jan 2, 2006`)
+		}
+		if strings.Contains(key, `Time`) || strings.Contains(key, `Date`) {
+			return time.Unix(int64(value), 0).Format(`Jan 2, 2006 3:04pm`)
+		}
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return value
+	case nil:
+		return `-`
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// Returns the fraction such as dividend yield as percent.
+// -----------------------------------------------------------------------------
+func fraction(value interface{}, code string) string {
+	if v, ok := number(value); ok {
+		return percent(v*100, code)
+	}
+	return `-`
+}
+
+// Returns Unix timestamp as date.
+// -----------------------------------------------------------------------------
+func timestamp(value interface{}, _ string) string {
+	if v, ok := number(value); ok && v > 0 {
+		return time.Unix(int64(v), 0).Format(`Jan 2, 2006`)
+	}
+	return `-`
+}
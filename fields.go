@@ -0,0 +1,108 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import "strings"
+
+// quoteField is the field of the Yahoo quote that is available as filter
+// variable and can be shown as optional column. The fields are looked up by
+// the name Yahoo uses, ex. forwardPE.
+type quoteField struct {
+	key       string                           // Yahoo field name, used as the filter variable and the column name.
+	title     string                           // Column title to display in the header.
+	width     int                              // Column width.
+	formatter func(interface{}, string) string // Function to format the value, nil for text fields.
+}
+
+// Yahoo quote fields available in addition to the ones shown by default.
+// The text fields are blank strings in filters when the data provider has
+// not returned them, and the numeric fields are zeros.
+var quoteFields = []quoteField{
+	{`shortName`, `Short Name`, 20, nil},
+	{`longName`, `Name`, 24, nil},
+	{`exchange`, `Exch`, 6, nil},
+	{`fullExchangeName`, `Exchange`, 12, nil},
+	{`quoteType`, `Type`, 10, nil},
+	{`marketState`, `State`, 9, nil},
+	{`region`, `Region`, 7, nil},
+	{`financialCurrency`, `FinCcy`, 7, nil},
+	{`regularMarketPreviousClose`, `Prev Close`, 11, currency},
+	{`bid`, `Bid`, 10, currency},
+	{`ask`, `Ask`, 10, currency},
+	{`bidSize`, `BidSize`, 8, integer},
+	{`askSize`, `AskSize`, 8, integer},
+	{`preMarketPrice`, `PreMkt`, 10, currency},
+	{`preMarketChange`, `PreMktChg`, 10, currency},
+	{`preMarketChangePercent`, `PreMktChg%`, 11, percent},
+	{`postMarketPrice`, `AfterMkt`, 10, currency},
+	{`postMarketChange`, `AfterMktChg`, 12, currency},
+	{`postMarketChangePercent`, `AfterMktChg%`, 13, percent},
+	{`averageDailyVolume10Day`, `AvgVol 10d`, 11, integer},
+	{`averageDailyVolume3Month`, `AvgVol 3M`, 11, integer},
+	{`fiftyDayAverage`, `50d Avg`, 10, currency},
+	{`fiftyDayAverageChangePercent`, `50d Avg%`, 10, fraction},
+	{`twoHundredDayAverage`, `200d Avg`, 10, currency},
+	{`twoHundredDayAverageChangePercent`, `200d Avg%`, 10, fraction},
+	{`fiftyTwoWeekHighChangePercent`, `52w High%`, 10, fraction},
+	{`fiftyTwoWeekLowChangePercent`, `52w Low%`, 10, fraction},
+	{`sharesOutstanding`, `Shares`, 11, integer},
+	{`trailingPE`, `P/E`, 9, blank},
+	{`forwardPE`, `Fwd P/E`, 9, blank},
+	{`priceToBook`, `P/B`, 9, blank},
+	{`bookValue`, `Book`, 10, currency},
+	{`epsTrailingTwelveMonths`, `EPS`, 9, currency},
+	{`epsForward`, `Fwd EPS`, 9, currency},
+	{`trailingAnnualDividendRate`, `Div Rate`, 9, currency},
+	{`trailingAnnualDividendYield`, `Div Yield`, 10, fraction},
+	{`dividendDate`, `Div Date`, 14, timestamp},
+	{`earningsTimestamp`, `Earnings`, 14, timestamp},
+}
+
+// column returns the optional layout column that shows the field.
+func (field quoteField) column() Column {
+	if field.formatter == nil {
+		return Column{field.width, field.key, field.title, text, nil}
+	}
+	return Column{field.width, field.key, field.title, field.formatter, nil}
+}
+
+// quoteFieldValues adds the Yahoo quote fields of the given stock to the
+// filter variables.
+func quoteFieldValues(stock *Stock, values map[string]interface{}) {
+	for _, field := range quoteFields {
+		if field.formatter == nil {
+			values[field.key] = strings.TrimSpace(stringField(stock.Fields, field.key))
+		} else {
+			value, _ := number(stock.Fields[field.key])
+			values[field.key] = value
+		}
+	}
+}
+
+// Returns true if the column shows the Yahoo quote field or the annotation
+// and therefore is hidden unless the user chooses to show it.
+// -----------------------------------------------------------------------------
+func optionalColumn(name string) bool {
+	for _, field := range quoteFields {
+		if field.key == name {
+			return true
+		}
+	}
+	for _, column := range annotationColumns {
+		if column.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the text field as is, or - if it's blank or not available.
+// -----------------------------------------------------------------------------
+func text(value interface{}, _ string) string {
+	if str, ok := value.(string); ok && str != `` {
+		return str
+	}
+	return `-`
+}
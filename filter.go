@@ -5,8 +5,13 @@
 package mop
 
 import (
-	"strconv"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"unicode"
+
+	"github.com/Knetic/govaluate"
 )
 
 // Filter gets called to sort stock quotes by one of the columns. The
@@ -23,63 +28,13 @@ func NewFilter(profile *Profile) *Filter {
 	}
 }
 
-// Changes money and % notation to a plain float for math, comparisons.
-func stringToNumber(numberString string) float64 {
-	// If the string "$3.6B" is passed in, the returned float will be 3.6E+09.
-	// If 0.03% is passed in, the returned float will be 0.03 (NOT 0.0003!).
-	newString := strings.TrimSpace(numberString)           // Take off whitespace.
-	newString = strings.Replace(newString, "$", "", 1)     // Remove the $ symbol.
-	newString = strings.Replace(newString, "%", "", 1)     // Remove the $ symbol.
-	newString = strings.Replace(newString, "K", "E+3", 1)  // Thousand (kilo)
-	newString = strings.Replace(newString, "M", "E+6", 1)  // Million
-	newString = strings.Replace(newString, "B", "E+9", 1)  // Billion
-	newString = strings.Replace(newString, "T", "E+12", 1) // Trillion
-	finalValue, _ := strconv.ParseFloat(newString, 64)
-	return finalValue
-}
-
 // Apply builds a list of sort interface based on current sort
 // order, then calls sort.Sort to do the actual job.
 func (filter *Filter) Apply(stocks []Stock) []Stock {
 	var filteredStocks []Stock
 
-	for _, stock := range stocks {
-		values := make(map[string]interface{})
-		// Make conversions from the strings to floats where necessary.
-		values["ticker"] = strings.TrimSpace(stock.Ticker) // Remains string
-		values["last"] = stringToNumber(stock.LastTrade)
-		values["change"] = stringToNumber(stock.Change)
-		values["changePercent"] = stringToNumber(stock.ChangePct)
-		values["open"] = stringToNumber(stock.Open)
-		values["low"] = stringToNumber(stock.Low)
-		values["high"] = stringToNumber(stock.High)
-		values["low52"] = stringToNumber(stock.Low52)
-		values["high52"] = stringToNumber(stock.High52)
-		values["dividend"] = stringToNumber(stock.Dividend)
-		values["yield"] = stringToNumber(stock.Yield)
-		values["mktCap"] = stringToNumber(stock.MarketCap)
-		values["mktCapX"] = stringToNumber(stock.MarketCapX)
-		values["volume"] = stringToNumber(stock.Volume)
-		values["avgVolume"] = stringToNumber(stock.AvgVolume)
-		values["pe"] = stringToNumber(stock.PeRatio)
-		values["peX"] = stringToNumber(stock.PeRatioX)
-		values["currency"] = strings.TrimSpace(stock.Currency)
-		values["direction"] = stock.Direction // Remains int.
-
-		// Extract market from ticker
-		ticker, ok := values["ticker"].(string)
-		if ok {
-			if strings.Contains(ticker, ".") {
-				parts := strings.Split(ticker, ".")
-				values["market"] = parts[len(parts)-1]
-			} else {
-				values["market"] = "US"
-			}
-		} else {
-			values["market"] = ""
-		}
-
-		result, err := filter.profile.filterExpression.Evaluate(values)
+	for i := range stocks {
+		result, err := filter.profile.filterExpression.Evaluate(filterValues(&stocks[i]))
 		if err != nil {
 			// The filter isn't working, so reset to no filter.
 			filter.profile.Filter = ""
@@ -99,9 +54,209 @@ func (filter *Filter) Apply(stocks []Stock) []Stock {
 		}
 
 		if truthy {
-			filteredStocks = append(filteredStocks, stock)
+			filteredStocks = append(filteredStocks, stocks[i])
 		}
 	}
 
 	return filteredStocks
 }
+
+// Names of the variables passed to the functions as hidden first argument,
+// see compileExpression. They are not valid identifiers and checkNames
+// rejects them so that they can't be used in the expressions otherwise.
+const (
+	tagsArgument         = `#tags`
+	notAvailableArgument = `#notAvailable`
+)
+
+// compileExpression parses the filter, alert, or computed column expression
+// after making sure it only uses known functions and variables. The functions
+// that need to know about the stock, such as hasTag('semis'), get the stock's
+// variable passed as hidden first argument. The variables passed to isNA()
+// get passed by name.
+func compileExpression(str string) (*govaluate.EvaluableExpression, error) {
+	if err := checkNames(str); err != nil {
+		return nil, err
+	}
+	expr, err := govaluate.NewEvaluableExpressionWithFunctions(str, expressionFunctions)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := expr.Tokens()
+	var rewritten []govaluate.ExpressionToken
+	depth, byName := 0, 0 // byName is the depth of the isNA() arguments, if any.
+	for i, token := range tokens {
+		switch token.Kind {
+		case govaluate.CLAUSE:
+			depth++
+		case govaluate.CLAUSE_CLOSE:
+			if byName > 0 && depth == byName {
+				byName = 0
+			}
+			depth--
+		case govaluate.VARIABLE:
+			if byName > 0 && depth == byName {
+				token = govaluate.ExpressionToken{Kind: govaluate.STRING, Value: token.Value}
+			}
+		}
+		rewritten = append(rewritten, token)
+		if i == 0 || token.Kind != govaluate.CLAUSE || tokens[i-1].Kind != govaluate.FUNCTION {
+			continue
+		}
+
+		hidden := ``
+		switch reflect.ValueOf(tokens[i-1].Value).Pointer() {
+		case reflect.ValueOf(hasTagFunction).Pointer():
+			hidden = tagsArgument
+		case reflect.ValueOf(isNAFunction).Pointer():
+			hidden, byName = notAvailableArgument, depth
+		}
+		if hidden != `` {
+			rewritten = append(rewritten, govaluate.ExpressionToken{Kind: govaluate.VARIABLE, Value: hidden})
+			if i+1 < len(tokens) && tokens[i+1].Kind != govaluate.CLAUSE_CLOSE {
+				rewritten = append(rewritten, govaluate.ExpressionToken{Kind: govaluate.SEPARATOR, Value: `,`})
+			}
+		}
+	}
+
+	return govaluate.NewEvaluableExpressionFromTokens(rewritten)
+}
+
+// checkNames reports the first unknown function or variable in the expression
+// along with its position, counting from 1. The references to the saved
+// filters, ex. @momentum, are skipped.
+func checkNames(str string) error {
+	variables := filterValues(&Stock{})
+	delete(variables, tagsArgument)
+	delete(variables, notAvailableArgument)
+	runes := []rune(str)
+	for i := 0; i < len(runes); i++ {
+		switch ch := runes[i]; {
+		case ch == '\'' || ch == '"':
+			for i++; i < len(runes) && runes[i] != ch; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+		case ch == '[':
+			start := i
+			for i++; i < len(runes) && runes[i] != ']'; i++ {
+			}
+			if _, ok := variables[string(runes[start+1:i])]; i < len(runes) && !ok {
+				return fmt.Errorf("unknown variable `%s` at position %d", string(runes[start+1:i]), start+1)
+			}
+		case unicode.IsLetter(ch):
+			start := i
+			for i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) || runes[i+1] == '_' || runes[i+1] == '.') {
+				i++
+			}
+			name := string(runes[start : i+1])
+			next := i + 1
+			for next < len(runes) && unicode.IsSpace(runes[next]) {
+				next++
+			}
+			switch {
+			case start > 0 && runes[start-1] == '@':
+			case next < len(runes) && runes[next] == '(':
+				if _, ok := expressionFunctions[name]; !ok && name != `in` && name != `IN` {
+					return fmt.Errorf("unknown function `%s` at position %d", name, start+1)
+				}
+			case name == `true` || name == `false` || name == `in` || name == `IN`:
+			default:
+				if _, ok := variables[name]; !ok {
+					return fmt.Errorf("unknown variable `%s` at position %d", name, start+1)
+				}
+			}
+		case unicode.IsDigit(ch):
+			for i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++ // Skip the whole number so that its digits don't get taken for the variable names.
+			}
+		}
+	}
+	return nil
+}
+
+// filterValues returns the variables available in filter expressions for
+// the given stock.
+func filterValues(stock *Stock) map[string]interface{} {
+	values := make(map[string]interface{})
+	quoteFieldValues(stock, values)
+	// Values that are not available are treated as zeros.
+	values["ticker"] = strings.TrimSpace(stock.Ticker) // Remains string
+	values["last"] = stock.LastTrade.Value
+	values["change"] = stock.Change.Value
+	values["changePercent"] = stock.ChangePct.Value
+	values["open"] = stock.Open.Value
+	values["low"] = stock.Low.Value
+	values["high"] = stock.High.Value
+	values["low52"] = stock.Low52.Value
+	values["high52"] = stock.High52.Value
+	values["dividend"] = stock.Dividend.Value
+	values["yield"] = stock.Yield.Value
+	values["mktCap"] = float64(stock.MarketCap.Value)
+	values["mktCapX"] = float64(stock.MarketCapX.Value)
+	values["volume"] = float64(stock.Volume.Value)
+	values["avgVolume"] = float64(stock.AvgVolume.Value)
+	values["pe"] = stock.PeRatio.Value
+	values["peX"] = stock.PeRatioX.Value
+	values["currency"] = strings.TrimSpace(stock.Currency)
+	values["direction"] = stock.Direction // Remains int.
+	values["session"] = stock.Session
+	values["tags"] = stock.Tags
+	values["note"] = stock.Note
+	values["alias"] = stock.Alias
+	values["advancing"] = stock.Direction > 0
+
+	// Extract market from ticker
+	ticker := values["ticker"].(string)
+	if strings.Contains(ticker, ".") {
+		parts := strings.Split(ticker, ".")
+		values["market"] = parts[len(parts)-1]
+	} else {
+		values["market"] = "US"
+	}
+	values[notAvailableArgument] = notAvailable(stock, values)
+	values[tagsArgument] = stock.Tags
+
+	return values
+}
+
+// Returns comma separated names of the filter variables that are not
+// available for the given stock, ex. pe of the stock that has no earnings.
+// The text variables are not available when blank.
+// -----------------------------------------------------------------------------
+func notAvailable(stock *Stock, values map[string]interface{}) string {
+	valid := map[string]bool{
+		`last`:          stock.LastTrade.Valid,
+		`change`:        stock.Change.Valid,
+		`changePercent`: stock.ChangePct.Valid,
+		`open`:          stock.Open.Valid,
+		`low`:           stock.Low.Valid,
+		`high`:          stock.High.Valid,
+		`low52`:         stock.Low52.Valid,
+		`high52`:        stock.High52.Valid,
+		`dividend`:      stock.Dividend.Valid,
+		`yield`:         stock.Yield.Valid,
+		`mktCap`:        stock.MarketCap.Valid,
+		`mktCapX`:       stock.MarketCapX.Valid,
+		`volume`:        stock.Volume.Valid,
+		`avgVolume`:     stock.AvgVolume.Valid,
+		`pe`:            stock.PeRatio.Valid,
+		`peX`:           stock.PeRatioX.Valid,
+	}
+	for _, field := range quoteFields {
+		if field.formatter != nil {
+			_, valid[field.key] = number(stock.Fields[field.key])
+		}
+	}
+
+	var names []string
+	for name, value := range values {
+		if ok, found := valid[name]; found && !ok || value == `` {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, `,`)
+}
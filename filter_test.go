@@ -0,0 +1,120 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import "testing"
+
+func TestCheckNames(t *testing.T) {
+	tests := []struct {
+		expression string
+		err        string // Blank if the names are fine.
+	}{
+		{`last > 100 && changePercent < -2`, ``},
+		{`lats > 100`, "unknown variable `lats` at position 1"},
+		{`last > 100 && chnage < 0`, "unknown variable `chnage` at position 15"},
+		{`abs(changePercent) > 3 && max(pe, peX) < 20`, ``},
+		{`abs (changePercent) > 3`, ``},
+		{`last > 1 && avg(last, open) > 2`, "unknown function `avg` at position 13"},
+		{`[forwardPE] < 15 && [changePercent] > 0`, ``},
+		{`[forward PE] < 15`, "unknown variable `forward PE` at position 1"},
+		{`ticker == 'lats' || note == "chnage (me)"`, ``},
+		{`ticker == 'it\'s lats'`, ``},
+		{`ticker == 'AAPL' || ticker == 'x@y'`, ``},
+		{`@momentum && last < 50`, ``},
+		{`@momentum && lats < 50`, "unknown variable `lats` at position 14"},
+		{`'é' == ticker && lats > 0`, "unknown variable `lats` at position 18"},
+		{`last > 1e3 && volume > 2.5e6`, ``},
+		{`advancing == true || advancing == false`, ``},
+		{`ticker in ('AAPL', 'MSFT') || ticker IN ('GOOG')`, ``},
+		{`oneOf(ticker, 'AAPL') && hasTag('semis') && isNA(pe)`, ``},
+		{`tags == '' && alias == '' && note == ''`, ``},
+		{`notAvailable == ''`, "unknown variable `notAvailable` at position 1"},
+		{`[#notAvailable] == ''`, "unknown variable `#notAvailable` at position 1"},
+		{`[#tags] == ''`, "unknown variable `#tags` at position 1"},
+	}
+	for _, test := range tests {
+		err := checkNames(test.expression)
+		if test.err == `` && err != nil {
+			t.Errorf(`%s: got %v`, test.expression, err)
+		} else if test.err != `` && (err == nil || err.Error() != test.err) {
+			t.Errorf(`%s: got %v, want %s`, test.expression, err, test.err)
+		}
+	}
+}
+
+func TestCompileExpression(t *testing.T) {
+	stock := &Stock{
+		Ticker:    `ASML.AS`,
+		LastTrade: Float(600),
+		ChangePct: Float(-2.5),
+		High52:    Float(1000),
+		PeRatioX:  Float(30),
+		Tags:      `semis, europe`,
+		Fields:    map[string]interface{}{`longName`: `ASML Holding N.V.`},
+	}
+	tests := []struct {
+		expression string
+		want       interface{} // Result, or nil if the expression fails.
+	}{
+		{`hasTag('semis')`, true},
+		{`hasTag('growth', 'Europe') && changePercent < 0`, true},
+		{`hasTag('growth')`, false},
+		{`hasTag()`, nil},
+		{`isNA(pe)`, true},
+		{`isNA(last)`, false},
+		{`isNA(pe) && !isNA(peX)`, true},
+		{`!(isNA(pe) || isNA(last))`, false},
+		{`isNA(note) && !isNA(tags)`, true},
+		{`isNA(pe) == isNA(high)`, true},
+		{`between(pct(last, high52), 50, 70) && isNA(pe)`, true},
+		{`max(isNA(pe) ? 1 : 0, abs(changePercent)) == 2.5`, true},
+		{`oneOf(isNA(pe), true) && hasTag(isNA(peX) ? 'x' : 'semis')`, true},
+		{`isNA(abs(pe))`, nil},
+		{`isNA()`, nil},
+		{`pct(last, high52) > 50`, true},
+		{`pct(last, pe) > 70`, false},
+		{`pct(last, pe) <= 70`, false},
+		{`oneOf(ticker, 'AAPL', 'ASML.AS')`, true},
+		{`ticker in ('AAPL', 'ASML.AS')`, true},
+		{`'x' in ('a', 'b')`, false},
+		{`ticker IN ('AAPL', 'MSFT')`, false},
+		{`ticker == 'isNA(pe)' || note == 'hasTag(x)'`, false},
+		{`matches([longName], '(?i)holding') && startsWith(ticker, 'AS')`, true},
+		{`market == 'AS' && advancing == false`, true},
+	}
+	for _, test := range tests {
+		expr, err := compileExpression(test.expression)
+		if err != nil {
+			if test.want != nil {
+				t.Errorf(`%s: %v`, test.expression, err)
+			}
+			continue
+		}
+		got, err := expr.Evaluate(filterValues(stock))
+		if test.want == nil {
+			if err == nil {
+				t.Errorf(`%s: got %v, want error`, test.expression, got)
+			}
+		} else if err != nil || got != test.want {
+			t.Errorf(`%s: got %v (%v), want %v`, test.expression, got, err, test.want)
+		}
+	}
+}
+
+func TestNotAvailable(t *testing.T) {
+	stock := &Stock{Ticker: `AAPL`, LastTrade: Float(200), Tags: `tech`}
+	values := filterValues(stock)
+	list := values[notAvailableArgument].(string)
+	for _, name := range []string{`pe`, `high52`, `note`, `alias`} {
+		if found, _ := isNAFunction(list, name); found != true {
+			t.Errorf(`%s should not be available: %s`, name, list)
+		}
+	}
+	for _, name := range []string{`last`, `ticker`, `tags`, `market`, tagsArgument, notAvailableArgument} {
+		if found, _ := isNAFunction(list, name); found != false {
+			t.Errorf(`%s should be available: %s`, name, list)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Functions available in the filter, alert, and computed column expressions.
+var expressionFunctions = map[string]govaluate.ExpressionFunction{
+	`abs`:        absFunction,
+	`min`:        minFunction,
+	`max`:        maxFunction,
+	`pct`:        pctFunction,
+	`between`:    betweenFunction,
+	`startsWith`: startsWithFunction,
+	`matches`:    matchesFunction,
+	`oneOf`:      oneOfFunction,
+	`isNA`:       isNAFunction,
+	`hasTag`:     hasTagFunction,
+}
+
+// Regular expressions used by matches() keyed by the pattern so that they
+// get compiled once.
+var matchesCache sync.Map
+
+// Returns the absolute value of the number, ex. abs(changePercent) > 3.
+// -----------------------------------------------------------------------------
+func absFunction(args ...interface{}) (interface{}, error) {
+	values, err := numbers(`abs`, args, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	return math.Abs(values[0]), nil
+}
+
+// Returns the smallest of the numbers, ex. min(pe, peX).
+// -----------------------------------------------------------------------------
+func minFunction(args ...interface{}) (interface{}, error) {
+	values, err := numbers(`min`, args, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Min(result, value)
+	}
+	return result, nil
+}
+
+// Returns the largest of the numbers, ex. max(open, last).
+// -----------------------------------------------------------------------------
+func maxFunction(args ...interface{}) (interface{}, error) {
+	values, err := numbers(`max`, args, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Max(result, value)
+	}
+	return result, nil
+}
+
+// Returns the first number as percent of the second one, ex. pct(last, high52)
+// > 90 for the stocks trading within 10% of the 52-week high. The percent of
+// zero, ex. when the value is not available, is not a number so that it's
+// never greater or less than anything.
+// -----------------------------------------------------------------------------
+func pctFunction(args ...interface{}) (interface{}, error) {
+	values, err := numbers(`pct`, args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	if values[1] == 0 {
+		return math.NaN(), nil
+	}
+	return values[0] / values[1] * 100, nil
+}
+
+// Returns true if the first number is within the range given by the other
+// two, inclusive, ex. between(last, 10, 20).
+// -----------------------------------------------------------------------------
+func betweenFunction(args ...interface{}) (interface{}, error) {
+	values, err := numbers(`between`, args, 3, 3)
+	if err != nil {
+		return nil, err
+	}
+	return values[0] >= values[1] && values[0] <= values[2], nil
+}
+
+// Returns true if the string starts with any of the given prefixes, ex.
+// startsWith(ticker, 'BR').
+// -----------------------------------------------------------------------------
+func startsWithFunction(args ...interface{}) (interface{}, error) {
+	values, err := texts(`startsWith`, args, 2, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range values[1:] {
+		if strings.HasPrefix(values[0], prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns true if the string matches the regular expression, ex.
+// matches(longName, '(?i)bank').
+// -----------------------------------------------------------------------------
+func matchesFunction(args ...interface{}) (interface{}, error) {
+	values, err := texts(`matches`, args, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	regex, ok := matchesCache.Load(values[1])
+	if !ok {
+		compiled, err := regexp.Compile(values[1])
+		if err != nil {
+			return nil, fmt.Errorf("matches() can't use `%s`: %s", values[1], err)
+		}
+		regex, _ = matchesCache.LoadOrStore(values[1], compiled)
+	}
+	return regex.(*regexp.Regexp).MatchString(values[0]), nil
+}
+
+// Returns true if the first value equals any of the others, ex.
+// oneOf(ticker, 'AAPL', 'MSFT').
+// -----------------------------------------------------------------------------
+func oneOfFunction(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("oneOf() needs the value and the list to look for it in")
+	}
+	for _, arg := range args[1:] {
+		if x, ok := number(args[0]); ok {
+			if y, ok := number(arg); ok && x == y {
+				return true, nil
+			}
+		} else if args[0] == arg {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns true if the variable is not available, ex. isNA(pe) for the stock
+// that has no earnings. The first argument is the comma separated list of
+// the variables that are not available, and the variable itself is passed as
+// its name.
+// -----------------------------------------------------------------------------
+func isNAFunction(args ...interface{}) (interface{}, error) {
+	values, err := texts(`isNA`, args, 2, 2)
+	if err != nil {
+		return nil, fmt.Errorf("isNA() needs the variable to check, ex. isNA(pe)")
+	}
+	for _, name := range strings.Split(values[0], `,`) {
+		if name == values[1] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns true if the stock has any of the given tags, ex. hasTag('semis').
+// The first argument is the comma separated list of the stock tags.
+// -----------------------------------------------------------------------------
+func hasTagFunction(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("hasTag() needs the tag to look for")
+	}
+	tags, _ := args[0].(string)
+	list := strings.Split(tags, `,`)
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	for _, tag := range args[1:] {
+		if tag, ok := tag.(string); ok && hasTag(list, tag) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Converts the function arguments to numbers making sure there are at least
+// min and at most max of them; negative max means there is no limit.
+// -----------------------------------------------------------------------------
+func numbers(function string, args []interface{}, min, max int) ([]float64, error) {
+	if err := countArguments(function, args, min, max); err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		value, ok := number(arg)
+		if !ok {
+			return nil, fmt.Errorf("%s() needs numbers, not `%v`", function, arg)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// Converts the function arguments to strings making sure there are at least
+// min and at most max of them; negative max means there is no limit.
+// -----------------------------------------------------------------------------
+func texts(function string, args []interface{}, min, max int) ([]string, error) {
+	if err := countArguments(function, args, min, max); err != nil {
+		return nil, err
+	}
+	values := make([]string, len(args))
+	for i, arg := range args {
+		value, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() needs strings, not `%v`", function, arg)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// -----------------------------------------------------------------------------
+func countArguments(function string, args []interface{}, min, max int) error {
+	if len(args) < min || max >= 0 && len(args) > max {
+		if min == max {
+			return fmt.Errorf("%s() needs %d argument(s), got %d", function, min, len(args))
+		}
+		return fmt.Errorf("%s() needs at least %d argument(s), got %d", function, min, len(args))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpressionFunctions(t *testing.T) {
+	tests := []struct {
+		function string
+		args     []interface{}
+		want     interface{} // Result, or nil if the function fails.
+	}{
+		{`abs`, []interface{}{-2.5}, 2.5},
+		{`abs`, []interface{}{NullFloat{Value: -1, Valid: true}}, 1.0},
+		{`abs`, []interface{}{}, nil},
+		{`abs`, []interface{}{`text`}, nil},
+		{`min`, []interface{}{3.0, 1.0, 2.0}, 1.0},
+		{`min`, []interface{}{4.0}, 4.0},
+		{`max`, []interface{}{3.0, 1.0, 2.0}, 3.0},
+		{`max`, []interface{}{}, nil},
+		{`pct`, []interface{}{45.0, 90.0}, 50.0},
+		{`pct`, []interface{}{45.0, 0.0}, math.NaN()},
+		{`pct`, []interface{}{0.0, 0.0}, math.NaN()},
+		{`pct`, []interface{}{45.0}, nil},
+		{`between`, []interface{}{10.0, 10.0, 20.0}, true},
+		{`between`, []interface{}{20.0, 10.0, 20.0}, true},
+		{`between`, []interface{}{21.0, 10.0, 20.0}, false},
+		{`between`, []interface{}{10.0, 20.0}, nil},
+		{`startsWith`, []interface{}{`BRK-B`, `AA`, `BR`}, true},
+		{`startsWith`, []interface{}{`AAPL`, `BR`}, false},
+		{`startsWith`, []interface{}{`AAPL`, 1.0}, nil},
+		{`matches`, []interface{}{`Bank of America`, `(?i)bank`}, true},
+		{`matches`, []interface{}{`Apple`, `^bank`}, false},
+		{`matches`, []interface{}{`Apple`, `(`}, nil},
+		{`oneOf`, []interface{}{`MSFT`, `AAPL`, `MSFT`}, true},
+		{`oneOf`, []interface{}{`GOOG`, `AAPL`, `MSFT`}, false},
+		{`oneOf`, []interface{}{2.0, 1.0, NullInt{Value: 2, Valid: true}}, true},
+		{`oneOf`, []interface{}{`2`, 2.0}, false},
+		{`oneOf`, []interface{}{`MSFT`}, nil},
+		{`isNA`, []interface{}{`high,pe,peX`, `pe`}, true},
+		{`isNA`, []interface{}{`high,pe,peX`, `p`}, false},
+		{`isNA`, []interface{}{``, `pe`}, false},
+		{`isNA`, []interface{}{`pe`, 1.0}, nil},
+		{`hasTag`, []interface{}{`semis, europe`, `growth`, `europe`}, true},
+		{`hasTag`, []interface{}{`semis, europe`, `Semis`}, true},
+		{`hasTag`, []interface{}{`semis, europe`, `euro`}, false},
+		{`hasTag`, []interface{}{``, `semis`}, false},
+		{`hasTag`, []interface{}{`semis`}, nil},
+	}
+	for _, test := range tests {
+		got, err := expressionFunctions[test.function](test.args...)
+		switch want := test.want.(type) {
+		case nil:
+			if err == nil {
+				t.Errorf(`%s%v: got %v, want error`, test.function, test.args, got)
+			}
+		case float64:
+			if value, ok := got.(float64); err != nil || !ok || !(value == want || math.IsNaN(value) && math.IsNaN(want)) {
+				t.Errorf(`%s%v: got %v (%v), want %v`, test.function, test.args, got, err, want)
+			}
+		default:
+			if err != nil || got != want {
+				t.Errorf(`%s%v: got %v (%v), want %v`, test.function, test.args, got, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys to group stock quotes by as they get cycled through with the g key.
+// Besides these the stock quotes can be grouped by any column or filter
+// variable set as GroupBy in the profile.
+var groupKeys = []string{``, `advancing`, `market`, `exchange`, `currency`, `sector`, `tag`, `watchlist`}
+
+// stockGroup is the list of stock quotes that have the same value of the
+// group key, ex. the stocks traded on the same market.
+type stockGroup struct {
+	name   string  // Value of the group key shared by the stocks.
+	stocks []Stock // Stock quotes in the group.
+}
+
+// Regroup picks the next way to group the stock quotes, or the previous one
+// if step is negative: none, advancing/declining, market, exchange, currency,
+// sector, tag, or watchlist.
+func (profile *Profile) Regroup(step int) error {
+	current := 0
+	for i, key := range groupKeys {
+		if key == profile.GroupBy || key == `advancing` && profile.Grouped {
+			current = i
+		}
+	}
+	next := groupKeys[(current+step+len(groupKeys))%len(groupKeys)]
+
+	profile.Grouped, profile.GroupBy, profile.Collapsed = next == `advancing`, next, nil
+	if profile.Grouped {
+		profile.GroupBy = ``
+	}
+	return profile.Save()
+}
+
+// ToggleGroup collapses the group with the given name, or expands it if it
+// has been collapsed.
+func (profile *Profile) ToggleGroup(name string) error {
+	for i, collapsed := range profile.Collapsed {
+		if collapsed == name {
+			profile.Collapsed = append(profile.Collapsed[:i], profile.Collapsed[i+1:]...)
+			return profile.Save()
+		}
+	}
+	profile.Collapsed = append(profile.Collapsed, name)
+	return profile.Save()
+}
+
+// ToggleGroups expands all the groups if any of them has been collapsed, or
+// collapses all of them otherwise.
+func (quotes *Quotes) ToggleGroups() error {
+	profile := quotes.profile
+	if profile.GroupBy == `` {
+		return nil
+	}
+	if len(profile.Collapsed) > 0 {
+		profile.Collapsed = nil
+	} else {
+		for _, group := range groups(quotes.stocks, profile) {
+			profile.Collapsed = append(profile.Collapsed, group.name)
+		}
+	}
+	return profile.Save()
+}
+
+// GroupOf returns the name of the group of the given stock, or blank string
+// if the stock quotes are not grouped.
+func (profile *Profile) GroupOf(stock *Stock) string {
+	if profile.GroupBy == `` {
+		return ``
+	}
+	return groupName(stock, profile)
+}
+
+// collapsed returns true if the user has collapsed the group so that only
+// its header is shown.
+func (profile *Profile) collapsed(name string) bool {
+	for _, collapsed := range profile.Collapsed {
+		if collapsed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Splits the stock quotes into groups by the value of the GroupBy key. The
+// groups are sorted by name while the stocks keep their order within the
+// group.
+// -----------------------------------------------------------------------------
+func groups(stocks []Stock, profile *Profile) []stockGroup {
+	var list []stockGroup
+	index := map[string]int{}
+	for _, stock := range stocks {
+		name := groupName(&stock, profile)
+		i, ok := index[name]
+		if !ok {
+			i = len(list)
+			index[name] = i
+			list = append(list, stockGroup{name: name})
+		}
+		list[i].stocks = append(list[i].stocks, stock)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	return list
+}
+
+// Returns the value of the group key for the given stock, or - if it's not
+// available.
+// -----------------------------------------------------------------------------
+func groupName(stock *Stock, profile *Profile) string {
+	var value interface{}
+	switch profile.GroupBy {
+	case `exchange`:
+		value = stringField(stock.Fields, `fullExchangeName`)
+	case `sector`:
+		value = stringField(stock.Fields, `sector`)
+	case `tag`:
+		value = strings.Split(stock.Tags, `,`)[0] // The stocks with more than one tag go to the first one.
+	case `watchlist`:
+		value = watchlistOf(stock.Ticker, profile)
+	default:
+		value = sortValues(stock, []SortKey{{Column: profile.GroupBy}})[0]
+	}
+
+	if value, ok := sortable(value); ok {
+		if str, ok := value.(string); ok {
+			return strings.TrimSpace(str)
+		}
+		return fmt.Sprint(value)
+	}
+	return `-`
+}
+
+// Returns the name of the first watchlist other than the active one that
+// lists the stock, or the name of the active one if there is no other.
+// -----------------------------------------------------------------------------
+func watchlistOf(ticker string, profile *Profile) string {
+	for _, watchlist := range profile.Watchlists {
+		if watchlist == profile.Watchlist {
+			continue
+		}
+		for _, listed := range watchlist.Tickers {
+			if listed == ticker {
+				return watchlist.Name
+			}
+		}
+	}
+	return profile.Watchlist.Name
+}
+
+// Formats the group header: the group name, number of stocks, their average
+// change percent, and total value of the positions held in each currency.
+// -----------------------------------------------------------------------------
+func groupHeader(group stockGroup, collapsed bool) string {
+	marker := `▾`
+	if collapsed {
+		marker = `▸`
+	}
+	str := fmt.Sprintf(`<tag>%s %s</>  %d`, marker, group.name, len(group.stocks))
+	if len(group.stocks) == 1 {
+		str += ` stock`
+	} else {
+		str += ` stocks`
+	}
+
+	sum, count := 0.0, 0
+	for _, stock := range group.stocks {
+		if stock.ChangePct.Valid {
+			sum, count = sum+stock.ChangePct.Value, count+1
+		}
+	}
+	if count > 0 {
+		average := Float(sum / float64(count))
+		str += `  avg Change% ` + colorize(percent(average, ``), colorFor(average))
+	}
+	for _, total := range totals(group.stocks) {
+		str += `  Value ` + currency(total.Value, total.Currency)
+	}
+
+	return str
+}
@@ -7,13 +7,11 @@ package mop
 import (
 	"bytes"
 	"fmt"
-	"reflect"
-	"regexp"
+	"math"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
-	"unicode"
 )
 
 var currencies = map[string]string{
@@ -28,10 +26,11 @@ var currencies = map[string]string{
 // Column describes formatting rules for individual column within the list
 // of stock quotes.
 type Column struct {
-	width     int                    // Column width.
-	name      string                 // The name of the field in the Stock struct.
-	title     string                 // Column title to display in the header.
-	formatter func(...string) string // Optional function to format the contents of the column.
+	width     int                              // Column width.
+	name      string                           // The name of the field in the Stock struct.
+	title     string                           // Column title to display in the header.
+	formatter func(interface{}, string) string // Optional function to format the value given stock currency.
+	color     func(*Stock) string              // Optional function to pick the color of the value.
 }
 
 // Layout is used to format and display all the collected data, i.e. market
@@ -40,50 +39,81 @@ type Layout struct {
 	columns        []Column           // List of stock quotes columns.
 	sorter         *Sorter            // Pointer to sorting receiver.
 	filter         *Filter            // Pointer to filtering receiver.
-	regex          *regexp.Regexp     // Pointer to regular expression to align decimal points.
-	marketTemplate *template.Template // Pointer to template to format market data.
 	quotesTemplate *template.Template // Pointer to template to format the list of stock quotes.
+	stocks         []Stock            // Stock quotes as arranged when formatted last time.
+	rows           []int              // Line of each stock quote relative to the first one, and the line past the last one.
+	headers        map[int]string     // Names of the groups keyed by the line of the group header.
 }
 
 // Creates the layout and assigns the default values that stay unchanged.
 func NewLayout() *Layout {
 	layout := &Layout{}
 	layout.columns = []Column{
-		{-10, `Ticker`, `Ticker`, nil},
-		{10, `LastTrade`, `Last`, currency},
-		{10, `Change`, `Change`, currency},
-		{10, `ChangePct`, `Change%`, last},
-		{10, `Open`, `Open`, currency},
-		{10, `Low`, `Low`, currency},
-		{10, `High`, `High`, currency},
-		{10, `Low52`, `52w Low`, currency},
-		{10, `High52`, `52w High`, currency},
-		{11, `Volume`, `Volume`, integer},
-		{11, `AvgVolume`, `AvgVolume`, integer},
-		{9, `PeRatio`, `P/E`, blank},
-		{9, `Dividend`, `Dividend`, zero},
-		{9, `Yield`, `Yield`, percent},
-		{11, `MarketCap`, `MktCap`, currency},
-		{13, `PreOpen`, `PreMktChg%`, percent},
-		{13, `AfterHours`, `AfterMktChg%`, percent},
-	}
-	layout.regex = regexp.MustCompile(`(\.\d+)[TBMK]?$`)
-	layout.marketTemplate = buildMarketTemplate()
+		{-10, `Ticker`, `Ticker`, nil, rowColor},
+		{10, `LastTrade`, `Last`, currency, rowColor},
+		{10, `Change`, `Change`, currency, rowColor},
+		{10, `ChangePct`, `Change%`, percent, rowColor},
+		{10, `Open`, `Open`, currency, rowColor},
+		{10, `Low`, `Low`, currency, rowColor},
+		{10, `High`, `High`, currency, rowColor},
+		{10, `Low52`, `52w Low`, currency, rowColor},
+		{10, `High52`, `52w High`, currency, rowColor},
+		{11, `Volume`, `Volume`, integer, rowColor},
+		{11, `AvgVolume`, `AvgVolume`, integer, rowColor},
+		{9, `PeRatio`, `P/E`, blank, rowColor},
+		{9, `Dividend`, `Dividend`, zero, rowColor},
+		{9, `Yield`, `Yield`, percent, rowColor},
+		{11, `MarketCap`, `MktCap`, currency, rowColor},
+		{13, `PreOpen`, `PreMktChg%`, percent, signColor(`PreOpen`)},
+		{13, `AfterHours`, `AfterMktChg%`, percent, signColor(`AfterHours`)},
+		{12, `Value`, `Value`, currency, nil},
+		{12, `Cost`, `Cost`, currency, nil},
+		{12, `DayPnl`, `Day P&L`, currency, signColor(`DayPnl`)},
+		{12, `TotalPnl`, `Total P&L`, currency, signColor(`TotalPnl`)},
+		{11, `TotalPnlPct`, `Total P&L%`, percent, signColor(`TotalPnl`)},
+		{9, `Session`, `Session`, nil, nil},
+		{12, `Sparkline`, `Sparkline`, sparkline, trendColor},
+	}
 	layout.quotesTemplate = buildQuotesTemplate()
 
 	return layout
 }
 
-// Market merges given market data structure with the market template and
-// returns formatted string that includes highlighting markup.
+// Market formats the quotes of the market summary strip as configured in
+// the profile, and returns formatted string that includes highlighting
+// markup. The state of U.S. markets is shown at the end of the second line.
 func (layout *Layout) Market(market *Market) string {
-	highlight(market.Dow, market.Sp500, market.Nasdaq,
-		market.Tokyo, market.HongKong, market.London, market.Frankfurt,
-		market.Yield, market.Oil, market.Euro, market.Yen, market.Gold)
-	buffer := new(bytes.Buffer)
-	layout.marketTemplate.Execute(buffer, market)
+	lines := []string{``}
+	for i, item := range market.profile.MarketStrip {
+		if item.Break && i > 0 {
+			lines = append(lines, ``)
+		} else if i > 0 {
+			lines[len(lines)-1] += ` `
+		}
+		quote := market.Quote(item.Symbol)
+		if quote == nil {
+			quote = &MarketQuote{Symbol: item.Symbol}
+		}
+		lines[len(lines)-1] += `<tag>` + item.Label + `</> ` + marketQuote(quote, item)
+	}
 
-	return buffer.String()
+	state := ``
+	switch {
+	case market.IsClosed:
+		state = `U.S. markets closed`
+	case market.State == SessionPre:
+		state = `U.S. pre-market`
+	case market.State == SessionPost:
+		state = `U.S. after hours`
+	}
+	if line := len(lines) - 1; state != `` {
+		if line > 1 {
+			line = 1
+		}
+		lines[line] += ` <right>` + state + `</right>`
+	}
+
+	return strings.Join(lines, "\n")
 }
 
 // Quotes uses quotes template to format timestamp, stock quotes header,
@@ -99,16 +129,36 @@ func (layout *Layout) Quotes(quotes *Quotes) string {
 		}
 		errStr += quotes.market.errors
 	}
+	if ok, err := quotes.alerts.Ok(); !ok {
+		if errStr != "" {
+			errStr += " | "
+		}
+		errStr += err
+	}
+	if err := quotes.profile.ComputedErrors(); err != "" {
+		if errStr != "" {
+			errStr += " | "
+		}
+		errStr += err
+	}
 
 	vars := struct {
-		Now    string  // Current timestamp.
-		Header string  // Formatted header line.
-		Stocks []Stock // List of formatted stock quotes.
-		Errors string  // Formatted errors.
+		Now    string              // Current timestamp.
+		Market string              // Blank lines to leave room for the market summary.
+		Tabs   string              // Formatted watchlist tab bar.
+		Header string              // Formatted header line.
+		Stocks []map[string]string // List of formatted stock quotes.
+		Totals []string            // Formatted portfolio totals, one per currency.
+		Banner string              // Recently fired alerts.
+		Errors string              // Formatted errors.
 	}{
 		time.Now().Format(`3:04:05pm ` + zonename),
+		strings.Repeat("\n", quotes.profile.MarketLines()),
+		layout.Tabs(quotes.profile),
 		layout.Header(quotes.profile),
 		layout.prettify(quotes),
+		layout.totals(quotes),
+		quotes.alerts.Banner(),
 		errStr,
 	}
 
@@ -123,10 +173,11 @@ func (layout *Layout) Quotes(quotes *Quotes) string {
 // When the column editor is active it knows how to highlight currently
 // selected column title.
 func (layout *Layout) Header(profile *Profile) string {
-	str, selectedColumn := ``, profile.selectedColumn
+	str, selectedColumn, keys := ``, profile.selectedColumn, layout.sortKeys(profile)
 
-	for i, col := range layout.columns {
-		arrow := arrowFor(i, profile)
+	for _, col := range layout.visible(profile) {
+		i := layout.index(profile, col.name)
+		arrow := arrowFor(col.name, keys)
 		if i != selectedColumn {
 			str += fmt.Sprintf(`%*s`, col.width, arrow+col.title)
 		} else {
@@ -137,138 +188,272 @@ func (layout *Layout) Header(profile *Profile) string {
 	return `<u>` + str + `</u>`
 }
 
-// TotalColumns is the utility method for the column editor that returns
-// total number of columns.
-func (layout *Layout) TotalColumns() int {
-	return len(layout.columns)
+// Tabs formats the tab bar with the names of all the watchlists highlighting
+// the active one. The tab bar is blank if there is only one watchlist.
+func (layout *Layout) Tabs(profile *Profile) string {
+	if len(profile.Watchlists) < 2 {
+		return ``
+	}
+
+	str := ``
+	for i, watchlist := range profile.Watchlists {
+		tab := fmt.Sprintf(` %d:%s `, i+1, watchlist.Name)
+		if i == profile.ActiveWatchlist {
+			str += `<r>` + tab + `</r> `
+		} else {
+			str += `<tag>` + tab + `</> `
+		}
+	}
+
+	return str
 }
 
 // -----------------------------------------------------------------------------
-func (layout *Layout) prettify(quotes *Quotes) []Stock {
-	pretty := make([]Stock, len(quotes.stocks))
+func (layout *Layout) prettify(quotes *Quotes) []map[string]string {
+	profile := quotes.profile
+	stocks := layout.arrange(quotes)
+	list := []stockGroup{{stocks: stocks}}
+	if profile.GroupBy != `` {
+		list = groups(stocks, profile)
+	}
+	pretty := make([]map[string]string, 0, len(stocks)+len(list))
+	layout.stocks, layout.rows, layout.headers = nil, nil, map[int]string{}
 
 	//
 	// Iterate over the list of stocks to get the longest ticker name (some tickers will exceed the allotted 10 char length for the Ticker column)
 	// Save the longest ticker length and use max(longestlength, column.width) later in the second loop to keep the ticker indentations consistent
 	//
+	columns := layout.visible(profile)
 	tickerWidth := 0
-	for _, stock := range quotes.stocks {
-		value := reflect.ValueOf(&stock).Elem().FieldByName(`Ticker`).String()
-		currentLength := len(value)
-		if currentLength > tickerWidth {
+	for _, stock := range stocks {
+		name := stock.Ticker
+		if stock.Alias != `` {
+			name = stock.Alias
+		}
+		if currentLength := len(name); currentLength > tickerWidth {
 			tickerWidth = currentLength
 		}
 	}
 
 	//
-	// Iterate over the list of stocks and properly format all its columns.
+	// Iterate over the groups, if any, and the stocks in each group, and
+	// properly format all the columns. The stocks of collapsed groups are
+	// left out.
 	//
-	for i, stock := range quotes.stocks {
-		pretty[i].Direction = stock.Direction
-		pretty[i].RowColor = stock.RowColor
-		pretty[i].PreOpenColor = stock.PreOpenColor
-		pretty[i].AfterHoursColor = stock.AfterHoursColor
-		//
-		// Iterate over the list of stock columns. For each column name:
-		// - Get current column value.
-		// - If the column has the formatter method then call it.
-		// - Set the column value padding it to the given width.
-		//
-		for _, column := range layout.columns {
-			// ex. value = stock.Change
-			value := reflect.ValueOf(&stock).Elem().FieldByName(column.name).String()
-			if column.formatter != nil {
-				// ex. value = currency(value)
-				value = column.formatter(value, stock.Currency)
+	line := 0
+	for _, group := range list {
+		collapsed := false
+		if profile.GroupBy != `` {
+			collapsed = profile.collapsed(group.name)
+			pretty = append(pretty, map[string]string{`Group`: groupHeader(group, collapsed)})
+			layout.headers[line] = group.name
+			line++
+		}
+		if collapsed {
+			continue
+		}
+		for _, stock := range group.stocks {
+			layout.stocks = append(layout.stocks, stock)
+			layout.rows = append(layout.rows, line)
+			pretty = append(pretty, layout.format(stock, columns, tickerWidth, profile.ExtendedHours))
+			line++
+			if pretty[len(pretty)-1][`Extended`] != `` {
+				line++
 			}
-			// ex. pretty[i].Change = layout.pad(value, 10)
-			if column.name == `Ticker` && (0-tickerWidth) < column.width {
-				column.width = (0 - tickerWidth)
+		}
+	}
+	layout.rows = append(layout.rows, line)
+
+	return pretty
+}
+
+// format formats all the columns of the stock quote and the extended hours
+// row, if any.
+func (layout *Layout) format(stock Stock, columns []Column, tickerWidth int, extendedHours string) map[string]string {
+	pretty := map[string]string{}
+	price, change, changePct, at, extended := stock.extended()
+	if extended && extendedHours == ExtendedReplace {
+		stock.LastTrade, stock.Change, stock.ChangePct = price, change, changePct
+		stock.Direction = direction(change)
+	}
+	row := ``
+	for _, column := range columns {
+		pretty[column.name] = layout.cell(&stock, column, tickerWidth)
+		if column.color != nil {
+			row += colorize(pretty[column.name], column.color(&stock))
+		} else {
+			row += pretty[column.name]
+		}
+	}
+	pretty[`Row`] = row
+	//
+	// The extended hours row shows the session name in the Ticker column
+	// followed by the price and change columns, if they go first, and
+	// the time of the last trade.
+	//
+	if extended && extendedHours == ExtendedRow {
+		row := &Stock{
+			Ticker:    `  ` + stock.Session,
+			LastTrade: price,
+			Change:    change,
+			ChangePct: changePct,
+			Currency:  stock.Currency,
+		}
+		str := ``
+		for _, column := range columns {
+			if column.name != `Ticker` && column.name != `LastTrade` && column.name != `Change` && column.name != `ChangePct` {
+				break
 			}
-			reflect.ValueOf(&pretty[i]).Elem().FieldByName(column.name).SetString(layout.pad(value, column.width))
+			str += layout.cell(row, column, tickerWidth)
+		}
+		if !at.IsZero() {
+			str += ` at ` + at.Format(`3:04pm`)
 		}
+		pretty[`Extended`] = colorize(str, colorFor(change))
 	}
 
+	return pretty
+}
+
+// cell formats the value of the given column and pads it to the column width.
+// The Ticker column is at least as wide as the longest ticker.
+func (layout *Layout) cell(stock *Stock, column Column, tickerWidth int) string {
+	// ex. value = stock.Change
+	value := stock.value(column.name)
+	if column.name == `Ticker` && stock.Alias != `` {
+		value = stock.Alias // Show the alias instead of the ticker.
+	}
+	str := fmt.Sprint(value)
+	if column.formatter != nil {
+		// ex. str = currency(value, `USD`)
+		str = column.formatter(value, stock.Currency)
+	}
+	// ex. layout.pad(str, 10)
+	if column.name == `Ticker` {
+		if (0 - tickerWidth) < column.width {
+			column.width = (0 - tickerWidth)
+		}
+	} else if runes := []rune(str); len(runes) >= abs(column.width) {
+		if _, ok := value.(string); ok {
+			str = string(runes[:abs(column.width)-2]) + `…` // Leave room for the space between the columns.
+		}
+	}
+	return layout.pad(str, column.width)
+}
+
+// totals formats portfolio totals line for each currency of the positions
+// held. All the positions are counted regardless of the filter.
+func (layout *Layout) totals(quotes *Quotes) []string {
+	var lines []string
+
+	for _, total := range totals(quotes.stocks) {
+		pnlPct := NullFloat{}
+		if total.Cost != 0 {
+			pnlPct = Float(total.TotalPnl / total.Cost * 100)
+		}
+		lines = append(lines, fmt.Sprintf(`<tag>Portfolio %s</> Value %s Cost %s Day P&L %s Total P&L %s`,
+			total.Currency,
+			currency(total.Value, total.Currency),
+			currency(total.Cost, total.Currency),
+			colorize(currency(total.DayPnl, total.Currency), colorFor(Float(total.DayPnl))),
+			colorize(currency(total.TotalPnl, total.Currency)+` (`+percent(pnlPct, ``)+`)`, colorFor(Float(total.TotalPnl))),
+		))
+	}
+
+	return lines
+}
+
+// arrange filters, sorts, and groups the list of stock quotes as requested
+// by the user. The original list of quotes is left intact.
+func (layout *Layout) arrange(quotes *Quotes) []Stock {
+	stocks := make([]Stock, len(quotes.stocks))
+	copy(stocks, quotes.stocks)
+	updateSessions(stocks, time.Now()) // The session might have changed since the quotes were fetched.
+
 	profile := quotes.profile
+	annotate(stocks, profile) // The annotations might have changed since the quotes were fetched.
+	compute(stocks, profile)
 
 	if profile.Filter != "" { // Fix for blank display if invalid filter expression was cleared.
 		if profile.filterExpression != nil {
 			if layout.filter == nil { // Initialize filter on first invocation.
 				layout.filter = NewFilter(profile)
 			}
-			pretty = layout.filter.Apply(pretty)
+			stocks = layout.filter.Apply(stocks)
 		}
 	}
 
 	if layout.sorter == nil { // Initialize sorter on first invocation.
 		layout.sorter = NewSorter(profile)
 	}
-	layout.sorter.SortByCurrentColumn(pretty)
+	layout.sorter.Sort(stocks, layout.sortKeys(profile))
 	//
 	// Group stocks by advancing/declining unless sorted by Change or Change%
 	// in which case the grouping has been done already.
 	//
-	if profile.Grouped && (profile.SortColumn < 2 || profile.SortColumn > 3) {
-		pretty = group(pretty)
+	if keys := layout.sortKeys(profile); profile.Grouped && (len(keys) == 0 || keys[0].Column != `Change` && keys[0].Column != `ChangePct`) {
+		stocks = group(stocks)
+	} else if profile.GroupBy != `` {
+		list := groups(stocks, profile)
+		stocks = stocks[:0]
+		for _, group := range list {
+			stocks = append(stocks, group.stocks...)
+		}
 	}
 
-	return pretty
+	return stocks
 }
 
-// -----------------------------------------------------------------------------
-func (layout *Layout) pad(str string, width int) string {
-	match := layout.regex.FindStringSubmatch(str)
-	if len(match) > 0 {
-		switch len(match[1]) {
-		case 2:
-			str = strings.Replace(str, match[1], match[1]+`0`, 1)
-		case 4, 5:
-			str = strings.Replace(str, match[1], match[1][0:3], 1)
-		}
+// sortKeys returns the keys to sort the stock quotes by: the sort keys of
+// the watchlist if there are any, or the sort column.
+func (layout *Layout) sortKeys(profile *Profile) []SortKey {
+	if len(profile.SortKeys) > 0 {
+		return profile.SortKeys
 	}
-
-	return fmt.Sprintf(`%*s`, width, str)
+	if columns := layout.catalog(profile); profile.SortColumn < len(columns) {
+		return []SortKey{{Column: columns[profile.SortColumn].name, Descending: !profile.Ascending}}
+	}
+	return nil
 }
 
 // -----------------------------------------------------------------------------
-func buildMarketTemplate() *template.Template {
-	markup := `<tag>Dow</> {{.Dow.change}} ({{.Dow.percent}}) at {{.Dow.latest}} <tag>S&P 500</> {{.Sp500.change}} ({{.Sp500.percent}}) at {{.Sp500.latest}} <tag>NASDAQ</> {{.Nasdaq.change}} ({{.Nasdaq.percent}}) at {{.Nasdaq.latest}}
-<tag>Tokyo</> {{.Tokyo.change}} ({{.Tokyo.percent}}) at {{.Tokyo.latest}} <tag>HK</> {{.HongKong.change}} ({{.HongKong.percent}}) at {{.HongKong.latest}} <tag>London</> {{.London.change}} ({{.London.percent}}) at {{.London.latest}} <tag>Frankfurt</> {{.Frankfurt.change}} ({{.Frankfurt.percent}}) at {{.Frankfurt.latest}} {{if .IsClosed}}<right>U.S. markets closed</right>{{end}}
-<tag>10-Year Yield</> {{.Yield.latest}} ({{.Yield.change}}) <tag>Euro</> ${{.Euro.latest}} ({{.Euro.change}}) <tag>Yen</> ¥{{.Yen.latest}} ({{.Yen.change}}) <tag>Oil</> ${{.Oil.latest}} ({{.Oil.change}}) <tag>Gold</> ${{.Gold.latest}} ({{.Gold.change}})`
-
-	return template.Must(template.New(`market`).Parse(markup))
+func (layout *Layout) pad(str string, width int) string {
+	return fmt.Sprintf(`%*s`, width, str)
 }
 
 // -----------------------------------------------------------------------------
 func buildQuotesTemplate() *template.Template {
-	markup := `<right><time>{{.Now}}</></right>
-{{if .Errors}}<loss>{{.Errors}}</>{{else}}
-{{end}}
-
+	markup := `<right><time>{{.Now}}</></right>{{.Market}}{{.Tabs}}{{if .Errors}}<right><loss>{{.Errors}}</></right>{{else if .Banner}}<right><r> {{.Banner}} </r></right>{{end}}
 
 <header>{{.Header}}</>
-{{range.Stocks}}{{if ne .RowColor ""}}<{{.RowColor}}>{{end}}{{.Ticker}}{{.LastTrade}}{{.Change}}{{.ChangePct}}{{.Open}}{{.Low}}{{.High}}{{.Low52}}{{.High52}}{{.Volume}}{{.AvgVolume}}{{.PeRatio}}{{.Dividend}}{{.Yield}}{{.MarketCap}}</>{{if ne .PreOpenColor ""}}<{{.PreOpenColor}}>{{end}}{{.PreOpen}}</>{{if ne .AfterHoursColor ""}}<{{.AfterHoursColor}}>{{end}}{{.AfterHours}}</>
-{{end}}`
+{{range.Stocks}}{{if .Group}}{{.Group}}
+{{else}}{{.Row}}
+{{if .Extended}}{{.Extended}}
+{{end}}{{end}}{{end}}{{if .Totals}}
+{{range .Totals}}{{.}}
+{{end}}{{end}}`
 
 	return template.Must(template.New(`quotes`).Parse(markup))
 }
 
+// Formats the market quote in the style of the market summary item.
 // -----------------------------------------------------------------------------
-func highlight(collections ...map[string]string) {
-	for _, collection := range collections {
-		change := collection[`change`]
-		if change[len(change)-1:] == `%` {
-			change = change[0 : len(change)-1]
-		}
-		adv, err := strconv.ParseFloat(change, 64)
-		if err == nil {
-			if adv < 0.0 {
-				collection[`change`] = `<loss>` + collection[`change`] + `</>`
-			} else if adv > 0.0 {
-				collection[`change`] = `<gain>` + collection[`change`] + `</>`
-			}
+func marketQuote(quote *MarketQuote, item MarketItem) string {
+	str := func(value NullFloat, prefix, suffix string) string {
+		if !value.Valid {
+			return `N/A`
 		}
+		return prefix + float2Str(value.Value) + suffix
+	}
+	price := str(quote.Price, item.Prefix, ``)
+
+	switch item.Style {
+	case StylePercent:
+		return price + ` (` + colorize(str(quote.ChangePct, ``, `%`), colorFor(quote.ChangePct)) + `)`
+	case StyleChange:
+		return price + ` (` + colorize(str(quote.Change, ``, ``), colorFor(quote.Change)) + `)`
 	}
+	return colorize(str(quote.Change, ``, ``), colorFor(quote.Change)) + ` (` + str(quote.ChangePct, ``, ``) + `) at ` + price
 }
 
 // -----------------------------------------------------------------------------
@@ -293,113 +478,181 @@ func group(stocks []Stock) []Stock {
 }
 
 // -----------------------------------------------------------------------------
-func arrowFor(column int, profile *Profile) string {
-	if column == profile.SortColumn {
-		if profile.Ascending {
+func arrowFor(column string, keys []SortKey) string {
+	for i, key := range keys {
+		if key.Column == column {
+			switch {
+			case i > 0 && key.Descending: // Secondary sort keys get hollow arrows.
+				return string('▽')
+			case i > 0:
+				return string('△')
+			case key.Descending:
+				return string('▼')
+			}
 			return string('▲')
 		}
-		return string('▼')
 	}
 	return ``
 }
 
+// Returns `gain` or `loss` markup tag depending on the sign of the value.
 // -----------------------------------------------------------------------------
-func blank(str ...string) string {
-	if len(str) < 1 {
-		return "ERR"
-	}
-	if (len(str[0]) == 3 && str[0][0:3] == `N/A`) || len(str[0]) == 0 {
-		return `-`
+func colorFor(value NullFloat) string {
+	if value.Valid {
+		if value.Value < 0.0 {
+			return `loss`
+		} else if value.Value > 0.0 {
+			return `gain`
+		}
 	}
+	return ``
+}
 
-	return str[0]
+// Wraps the string in the markup tag unless the tag is blank.
+// -----------------------------------------------------------------------------
+func colorize(str, tag string) string {
+	if tag == `` {
+		return str
+	}
+	return `<` + tag + `>` + str + `</>`
 }
 
+// Returns numeric value as float64 and true, or false if the value is not
+// available.
 // -----------------------------------------------------------------------------
-func zero(str ...string) string {
-	if len(str) < 2 {
-		return "ERR"
+func number(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case NullFloat:
+		return value.Value, value.Valid
+	case NullInt:
+		return float64(value.Value), value.Valid
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	case int:
+		return float64(value), true
 	}
-	if str[0] == `0.00` {
+	return 0, false
+}
+
+// Returns value with two decimal points or `-` if the value is not available.
+// -----------------------------------------------------------------------------
+func blank(value interface{}, _ string) string {
+	v, ok := number(value)
+	if !ok {
 		return `-`
 	}
 
-	return currency(str[0], str[1])
+	return fmt.Sprintf(`%.2f`, v)
 }
 
 // -----------------------------------------------------------------------------
-func last(str ...string) string {
-	if len(str) < 1 {
-		return "ERR"
-	}
-	if len(str[0]) >= 6 && str[0][0:6] == `N/A - ` {
-		return str[0][6:]
+func zero(value interface{}, code string) string {
+	if v, ok := number(value); !ok || v == 0.0 {
+		return `-`
 	}
 
-	return percent(str[0])
+	return currency(value, code)
 }
 
+// Returns the amount prefixed with the currency symbol. Large amounts are
+// abbreviated using K, M, B, and T units while amounts below one get extra
+// decimal points so that penny stocks remain readable.
 // -----------------------------------------------------------------------------
-func currency(str ...string) string {
-	if len(str) < 2 {
-		return "ERR"
+func currency(value interface{}, code string) string {
+	v, ok := number(value)
+	if !ok {
+		return `-`
 	}
 	// default to $
 	symbol := "$"
-	c, ok := currencies[str[1]]
-	if ok {
+	c, found := currencies[code]
+	if found {
 		symbol = c
 	}
-	if str[0] == `N/A` || len(str[0]) == 0 {
-		return `-`
+
+	sign := ``
+	if v < 0 {
+		sign, v = `-`, -v
 	}
-	if sign := str[0][0:1]; sign == `+` || sign == `-` {
-		return sign + symbol + str[0][1:]
+	if v != 0 && v < 1.0 {
+		return sign + symbol + fmt.Sprintf(`%.4f`, v)
 	}
 
-	return symbol + str[0]
+	return sign + symbol + humanize(v, 2)
 }
 
-// Returns percent value truncated at 2 decimal points.
+// Returns percent value rounded to 2 decimal points.
 // -----------------------------------------------------------------------------
-func percent(str ...string) string {
-	if len(str) < 1 {
-		return "ERR"
-	}
-	if str[0] == `N/A` || len(str[0]) == 0 {
+func percent(value interface{}, _ string) string {
+	v, ok := number(value)
+	if !ok {
 		return `-`
 	}
 
-	split := strings.Split(str[0], ".")
-	if len(split) == 2 {
-		digits := len(split[1])
-		if digits > 2 {
-			digits = 2
-		}
-		str[0] = split[0] + "." + split[1][0:digits]
+	return fmt.Sprintf(`%.2f%%`, v)
+}
+
+// Returns value as integer (no trailing digits after a '.'), or abbreviated
+// with the unit if the value is large.
+// -----------------------------------------------------------------------------
+func integer(value interface{}, _ string) string {
+	v, ok := number(value)
+	if !ok {
+		return `-`
 	}
-	if str[0][len(str[0])-1] != '%' {
-		str[0] += `%`
+	if math.Abs(v) > 1.0e5 {
+		return humanize(v, 2)
 	}
-	return str[0]
+
+	return strconv.FormatInt(int64(v), 10)
 }
 
-// Returns value as integer (no trailing digits after a '.').
+// Formats the number with given decimal points using K, M, B, and T units
+// for large numbers.
 // -----------------------------------------------------------------------------
-func integer(str ...string) string {
-	if len(str) < 1 {
-		return "ERR"
+func humanize(v float64, decimals int) string {
+	unit := ``
+	switch abs := math.Abs(v); {
+	case abs > 1.0e12:
+		v /= 1.0e12
+		unit = `T`
+	case abs > 1.0e9:
+		v /= 1.0e9
+		unit = `B`
+	case abs > 1.0e6:
+		v /= 1.0e6
+		unit = `M`
+	case abs > 1.0e5:
+		v /= 1.0e3
+		unit = `K`
 	}
-	if str[0] == `N/A` || len(str[0]) == 0 {
+
+	return strconv.FormatFloat(v, 'f', decimals, 64) + unit
+}
+
+// Draws the sparkline with block characters scaled between the lowest and
+// highest prices, or returns `-` if there is no price history.
+// -----------------------------------------------------------------------------
+func sparkline(value interface{}, _ string) string {
+	values, _ := value.([]float64)
+	if len(values) == 0 {
 		return `-`
 	}
+	low, high := values[0], values[0]
+	for _, value := range values {
+		low, high = math.Min(low, value), math.Max(high, value)
+	}
 
-	// Don't strip after the '.' if we have a value such as 123.45M
-	if unicode.IsDigit(rune(str[0][len(str[0])-1])) {
-		split := strings.Split(str[0], ".")
-		if len(split) == 2 {
-			return split[0]
+	blocks := []rune(`▁▂▃▄▅▆▇█`)
+	str := make([]rune, len(values))
+	for i, value := range values {
+		level := len(blocks) / 2
+		if high > low {
+			level = int((value - low) / (high - low) * float64(len(blocks)-1))
 		}
+		str[i] = blocks[level]
 	}
-	return str[0]
+	return string(str)
 }
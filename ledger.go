@@ -0,0 +1,441 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of ledger transactions.
+const (
+	Buy      = `buy`      // Shares bought.
+	Sell     = `sell`     // Shares sold.
+	Dividend = `dividend` // Cash dividend received.
+	Split    = `split`    // Additional shares received in a stock split.
+)
+
+// Transaction is a single ledger entry imported from the broker statement.
+type Transaction struct {
+	ID     string  // Fingerprint used to skip rows that have been imported already.
+	Date   string  // Trade date (YYYY-MM-DD).
+	Type   string  // One of buy, sell, dividend, or split.
+	Ticker string  // Stock ticker.
+	Shares float64 // Number of shares bought, sold, or received in a split.
+	Price  float64 `json:",omitempty"` // Price per share.
+	Amount float64 `json:",omitempty"` // Cash amount of the transaction.
+	Fees   float64 `json:",omitempty"` // Commissions and fees.
+}
+
+// Lot is a number of shares bought at the same time and price.
+type Lot struct {
+	Date   string  // Date the shares were bought.
+	Shares float64 // Number of shares remaining in the lot.
+	Cost   float64 // Cost per share including fees.
+}
+
+// Position is the state of the particular stock derived from the ledger.
+type Position struct {
+	Ticker    string  // Stock ticker.
+	Lots      []Lot   // Open lots in the order they were bought.
+	Realized  float64 // Realized gain or loss on the shares sold.
+	Dividends float64 // Total dividends received.
+}
+
+// CSVAction maps broker's description of the transaction to its type. The
+// action matches when broker's description starts with the given prefix
+// (case insensitive).
+type CSVAction struct {
+	Prefix string // Start of broker's transaction description.
+	Type   string // Transaction type, empty to skip the row.
+}
+
+// CSVMapping describes the layout of broker's CSV export by naming the
+// columns that hold transaction data.
+type CSVMapping struct {
+	Name        string      // Mapping name as given on the command line.
+	Date        string      // Trade date column.
+	Type        string      // Transaction type (action) column.
+	Ticker      string      // Stock ticker column.
+	Shares      string      // Number of shares column.
+	Price       string      // Price per share column.
+	Amount      string      // Cash amount column.
+	Fees        []string    // Columns with commissions and fees.
+	DateFormats []string    // Accepted date formats, see time.Parse.
+	Actions     []CSVAction // Mapping of actions to transaction types, first match wins.
+}
+
+// csvMappings lists built-in mappings for common broker export formats.
+var csvMappings = []CSVMapping{
+	{
+		Name: `mop`, Date: `Date`, Type: `Type`, Ticker: `Ticker`, Shares: `Shares`,
+		Price: `Price`, Amount: `Amount`, Fees: []string{`Fees`},
+		DateFormats: []string{`2006-01-02`},
+		Actions: []CSVAction{
+			{`buy`, Buy}, {`sell`, Sell}, {`dividend`, Dividend}, {`split`, Split},
+		},
+	},
+	{
+		Name: `schwab`, Date: `Date`, Type: `Action`, Ticker: `Symbol`, Shares: `Quantity`,
+		Price: `Price`, Amount: `Amount`, Fees: []string{`Fees & Comm`},
+		DateFormats: []string{`01/02/2006`},
+		Actions: []CSVAction{
+			{`buy`, Buy}, {`reinvest shares`, Buy}, {`sell`, Sell},
+			{`cash dividend`, Dividend}, {`qualified dividend`, Dividend},
+			{`non-qualified div`, Dividend}, {`reinvest dividend`, Dividend},
+			{`stock split`, Split},
+		},
+	},
+	{
+		Name: `fidelity`, Date: `Run Date`, Type: `Action`, Ticker: `Symbol`, Shares: `Quantity`,
+		Price: `Price ($)`, Amount: `Amount ($)`, Fees: []string{`Commission ($)`, `Fees ($)`},
+		DateFormats: []string{`01/02/2006`},
+		Actions: []CSVAction{
+			{`you bought`, Buy}, {`reinvestment`, Buy}, {`you sold`, Sell},
+			{`dividend received`, Dividend}, {`distribution`, Split},
+		},
+	},
+	{
+		Name: `vanguard`, Date: `Trade Date`, Type: `Transaction Type`, Ticker: `Symbol`, Shares: `Shares`,
+		Price: `Share Price`, Amount: `Net Amount`, Fees: []string{`Commission Fees`},
+		DateFormats: []string{`2006-01-02`, `01/02/2006`},
+		Actions: []CSVAction{
+			{`buy`, Buy}, {`reinvestment`, Buy}, {`sell`, Sell},
+			{`dividend`, Dividend}, {`stock split`, Split},
+		},
+	},
+}
+
+// bom is the byte order mark some brokers put at the start of CSV exports.
+const bom = "\ufeff"
+
+// Ledger keeps all the transactions imported so far and knows how to derive
+// current positions from them.
+type Ledger struct {
+	Transactions []Transaction // Imported transactions in chronological order.
+	filename     string        // Path to the file in which the ledger is stored.
+}
+
+// LoadLedger reads the ledger from the given file. Missing file yields an
+// empty ledger.
+func LoadLedger(filename string) (*Ledger, error) {
+	ledger := &Ledger{filename: filename}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return ledger, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ledger, json.Unmarshal(data, ledger)
+}
+
+// Save serializes the ledger using JSON and saves it.
+func (ledger *Ledger) Save() error {
+	data, err := json.MarshalIndent(ledger, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ledger.filename, data, 0o644)
+}
+
+// Import reads broker's CSV export and adds new transactions to the ledger.
+// Transactions that have been imported before are skipped, so importing
+// overlapping statements is safe. Rows with unknown actions are ignored.
+func (ledger *Ledger) Import(reader io.Reader, mapping *CSVMapping) (added, skipped int, err error) {
+	rows, err := readCSV(reader, mapping)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	existing := make(map[string]bool)
+	for _, transaction := range ledger.Transactions {
+		existing[transaction.ID] = true
+	}
+
+	// Identical rows within the same statement are legit (ex. two buys at
+	// the same price on the same day), so tell them apart by their count.
+	seen := make(map[string]int)
+	for _, row := range rows {
+		transaction, err := mapping.transaction(row)
+		if err != nil {
+			return added, skipped, err
+		}
+		if transaction == nil {
+			continue
+		}
+		key := transaction.key()
+		seen[key]++
+		transaction.ID = fingerprint(key + `|` + strconv.Itoa(seen[key]))
+		if existing[transaction.ID] {
+			skipped++
+			continue
+		}
+		existing[transaction.ID] = true
+		ledger.Transactions = append(ledger.Transactions, *transaction)
+		added++
+	}
+
+	sort.SliceStable(ledger.Transactions, func(i, j int) bool {
+		return ledger.Transactions[i].Date < ledger.Transactions[j].Date
+	})
+
+	return added, skipped, nil
+}
+
+// Positions replays all the transactions and returns the resulting positions
+// keyed by stock ticker. Sold shares are matched against the lots on the
+// first in, first out basis.
+func (ledger *Ledger) Positions() map[string]*Position {
+	positions := make(map[string]*Position)
+
+	for _, transaction := range ledger.Transactions {
+		position, ok := positions[transaction.Ticker]
+		if !ok {
+			position = &Position{Ticker: transaction.Ticker}
+			positions[transaction.Ticker] = position
+		}
+		position.apply(&transaction)
+	}
+
+	return positions
+}
+
+// Shares returns the number of shares held.
+func (position *Position) Shares() float64 {
+	shares := 0.0
+	for _, lot := range position.Lots {
+		shares += lot.Shares
+	}
+	return shares
+}
+
+// Holding converts the position into the holding stored in the profile. It
+// returns nil if no shares are held.
+func (position *Position) Holding() *Holding {
+	shares, cost := 0.0, 0.0
+	for _, lot := range position.Lots {
+		shares += lot.Shares
+		cost += lot.Shares * lot.Cost
+	}
+	if shares < 1e-9 {
+		return nil
+	}
+
+	return &Holding{Shares: shares, Cost: cost / shares, Purchased: position.Lots[0].Date}
+}
+
+// -----------------------------------------------------------------------------
+func (position *Position) apply(transaction *Transaction) {
+	switch transaction.Type {
+	case Buy:
+		total := transaction.Shares*transaction.Price + transaction.Fees
+		if transaction.Price == 0 {
+			total = math.Abs(transaction.Amount)
+		}
+		if transaction.Shares > 0 {
+			position.Lots = append(position.Lots, Lot{
+				Date:   transaction.Date,
+				Shares: transaction.Shares,
+				Cost:   total / transaction.Shares,
+			})
+		}
+	case Sell:
+		proceeds := transaction.Shares*transaction.Price - transaction.Fees
+		if transaction.Price == 0 {
+			proceeds = math.Abs(transaction.Amount)
+		}
+		remaining, cost := transaction.Shares, 0.0
+		for remaining > 1e-9 && len(position.Lots) > 0 {
+			lot := &position.Lots[0]
+			sold := math.Min(remaining, lot.Shares)
+			cost += sold * lot.Cost
+			lot.Shares -= sold
+			remaining -= sold
+			if lot.Shares < 1e-9 {
+				position.Lots = position.Lots[1:]
+			}
+		}
+		// Short sales are not supported: only the shares held are counted.
+		// Zero share rows, ex. cash in lieu or fees, don't sell anything.
+		if transaction.Shares > 0 {
+			sold := transaction.Shares - remaining
+			position.Realized += proceeds*sold/transaction.Shares - cost
+		}
+	case Dividend:
+		position.Dividends += math.Abs(transaction.Amount)
+	case Split:
+		held := position.Shares()
+		if held > 0 && transaction.Shares != 0 {
+			ratio := (held + transaction.Shares) / held
+			for i := range position.Lots {
+				position.Lots[i].Shares *= ratio
+				position.Lots[i].Cost /= ratio
+			}
+		}
+	}
+}
+
+// key returns the string that identifies the transaction contents.
+func (transaction *Transaction) key() string {
+	return strings.Join([]string{
+		transaction.Date,
+		transaction.Type,
+		transaction.Ticker,
+		strconv.FormatFloat(transaction.Shares, 'f', -1, 64),
+		strconv.FormatFloat(transaction.Price, 'f', -1, 64),
+		strconv.FormatFloat(transaction.Amount, 'f', -1, 64),
+		strconv.FormatFloat(transaction.Fees, 'f', -1, 64),
+	}, `|`)
+}
+
+// LookupMapping returns custom mapping with the given name, or the built-in
+// one if there is no custom mapping.
+func LookupMapping(name string, custom []CSVMapping) (*CSVMapping, error) {
+	for _, mappings := range [][]CSVMapping{custom, csvMappings} {
+		for i := range mappings {
+			if strings.EqualFold(mappings[i].Name, name) {
+				return &mappings[i], nil
+			}
+		}
+	}
+
+	names := []string{}
+	for _, mapping := range csvMappings {
+		names = append(names, mapping.Name)
+	}
+	return nil, fmt.Errorf("unknown mapping `%s` (built-in mappings: %s)", name, strings.Join(names, `, `))
+}
+
+// transaction converts CSV row to the transaction. It returns nil if the
+// row describes transaction of unknown type, or it's not a transaction at
+// all (ex. totals line at the bottom of the statement).
+func (mapping *CSVMapping) transaction(row map[string]string) (*Transaction, error) {
+	action := strings.ToLower(strings.TrimSpace(row[mapping.Type]))
+	kind := ``
+	for _, a := range mapping.Actions {
+		if strings.HasPrefix(action, strings.ToLower(a.Prefix)) {
+			kind = a.Type
+			break
+		}
+	}
+	ticker := strings.ToUpper(strings.TrimSpace(row[mapping.Ticker]))
+	if kind == `` || ticker == `` {
+		return nil, nil
+	}
+
+	date, err := mapping.date(row[mapping.Date])
+	if err != nil {
+		return nil, err
+	}
+	transaction := &Transaction{Date: date, Type: kind, Ticker: ticker}
+	transaction.Shares = math.Abs(parseAmount(row[mapping.Shares]))
+	transaction.Price = math.Abs(parseAmount(row[mapping.Price]))
+	transaction.Amount = parseAmount(row[mapping.Amount])
+	for _, column := range mapping.Fees {
+		transaction.Fees += math.Abs(parseAmount(row[column]))
+	}
+
+	return transaction, nil
+}
+
+// -----------------------------------------------------------------------------
+func (mapping *CSVMapping) date(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	// Schwab reports settlement dates as `03/15/2024 as of 03/14/2024`.
+	if i := strings.Index(value, ` as of `); i > 0 {
+		value = value[i+len(` as of `):]
+	}
+	for _, format := range mapping.DateFormats {
+		if date, err := time.Parse(format, value); err == nil {
+			return date.Format(`2006-01-02`), nil
+		}
+	}
+
+	return ``, fmt.Errorf("invalid date `%s`", value)
+}
+
+// readCSV reads CSV rows as maps keyed by column name. Lines above the header
+// row (ex. account name) are skipped, and so are rows that do not have all
+// the columns. Statements listing most recent transactions first are
+// reversed.
+func readCSV(reader io.Reader, mapping *CSVMapping) ([]map[string]string, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	var header []string
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			for _, field := range record {
+				if strings.TrimSpace(strings.TrimPrefix(field, bom)) == mapping.Date {
+					header = record
+					break
+				}
+			}
+			continue
+		}
+		if len(record) < len(header) {
+			continue
+		}
+		row := make(map[string]string, len(header))
+		for i, name := range header {
+			row[strings.TrimSpace(strings.TrimPrefix(name, bom))] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	if header == nil {
+		return nil, fmt.Errorf("header row with `%s` column not found", mapping.Date)
+	}
+
+	if len(rows) > 1 {
+		first, _ := mapping.date(rows[0][mapping.Date])
+		last, _ := mapping.date(rows[len(rows)-1][mapping.Date])
+		if first > last {
+			for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+				rows[i], rows[j] = rows[j], rows[i]
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// parseAmount converts amounts like `$1,234.56` or `(12.00)` to a number.
+func parseAmount(value string) float64 {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, `(`) && strings.HasSuffix(value, `)`)
+	value = strings.NewReplacer(`$`, ``, `,`, ``, `(`, ``, `)`, ``, ` `, ``).Replace(value)
+	amount, _ := strconv.ParseFloat(value, 64)
+	if negative {
+		amount = -amount
+	}
+	return amount
+}
+
+// fingerprint returns short hash of the given string.
+func fingerprint(str string) string {
+	sum := sha1.Sum([]byte(str))
+	return hex.EncodeToString(sum[:8])
+}
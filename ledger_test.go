@@ -0,0 +1,246 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPositionApply(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		lots         []Lot
+		realized     float64
+		dividends    float64
+	}{
+		{
+			name: `buy with fees`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 10, Price: 100, Fees: 10},
+			},
+			lots: []Lot{{`2024-01-02`, 10, 101}},
+		},
+		{
+			name: `buy by amount`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 4, Amount: -1000},
+			},
+			lots: []Lot{{`2024-01-02`, 4, 250}},
+		},
+		{
+			name: `sell first in first out`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 10, Price: 100},
+				{Date: `2024-02-01`, Type: Buy, Shares: 10, Price: 200},
+				{Date: `2024-03-01`, Type: Sell, Shares: 15, Price: 300},
+			},
+			lots:     []Lot{{`2024-02-01`, 5, 200}},
+			realized: 15*300 - (10*100 + 5*200),
+		},
+		{
+			name: `sell more than held`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 10, Price: 100},
+				{Date: `2024-03-01`, Type: Sell, Shares: 20, Price: 150, Fees: 20},
+			},
+			realized: (20*150-20)*10/20 - 10*100,
+		},
+		{
+			name: `zero share sell`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 10, Price: 100},
+				{Date: `2024-03-01`, Type: Sell, Amount: 12.34},
+			},
+			lots: []Lot{{`2024-01-02`, 10, 100}},
+		},
+		{
+			name: `split`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Shares: 10, Price: 100},
+				{Date: `2024-02-01`, Type: Buy, Shares: 10, Price: 50},
+				{Date: `2024-06-10`, Type: Split, Shares: 60},
+			},
+			lots: []Lot{{`2024-01-02`, 40, 25}, {`2024-02-01`, 40, 12.5}},
+		},
+		{
+			name: `split of nothing`,
+			transactions: []Transaction{
+				{Date: `2024-06-10`, Type: Split, Shares: 10},
+			},
+		},
+		{
+			name: `dividends`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Dividend, Amount: 12.5},
+				{Date: `2024-04-02`, Type: Dividend, Amount: -7.5},
+			},
+			dividends: 20,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			position := &Position{}
+			for i := range test.transactions {
+				position.apply(&test.transactions[i])
+			}
+			if len(position.Lots) != len(test.lots) {
+				t.Fatalf(`got lots %v, want %v`, position.Lots, test.lots)
+			}
+			for i, lot := range position.Lots {
+				if lot.Date != test.lots[i].Date || !equal(lot.Shares, test.lots[i].Shares) || !equal(lot.Cost, test.lots[i].Cost) {
+					t.Errorf(`got lot %v, want %v`, lot, test.lots[i])
+				}
+			}
+			if !equal(position.Realized, test.realized) {
+				t.Errorf(`got realized %v, want %v`, position.Realized, test.realized)
+			}
+			if !equal(position.Dividends, test.dividends) {
+				t.Errorf(`got dividends %v, want %v`, position.Dividends, test.dividends)
+			}
+		})
+	}
+}
+
+func TestLedgerImportSkipsDuplicates(t *testing.T) {
+	mapping, _ := LookupMapping(`mop`, nil)
+	statement := `Date,Type,Ticker,Shares,Price,Amount,Fees
+2024-01-02,buy,AAPL,10,100,-1000,0
+2024-01-02,buy,AAPL,10,100,-1000,0
+2024-01-03,sell,AAPL,5,110,550,1
+`
+	overlapping := `Date,Type,Ticker,Shares,Price,Amount,Fees
+2024-01-03,sell,AAPL,5,110,550,1
+2024-01-04,dividend,AAPL,0,0,2.5,0
+`
+	tests := []struct {
+		statement string
+		added     int
+		skipped   int
+	}{
+		{statement, 3, 0}, // Identical rows within the statement are both kept.
+		{statement, 0, 3},
+		{overlapping, 1, 1},
+	}
+
+	ledger := &Ledger{}
+	for i, test := range tests {
+		added, skipped, err := ledger.Import(strings.NewReader(test.statement), mapping)
+		if err != nil {
+			t.Fatalf(`import #%d: %v`, i+1, err)
+		}
+		if added != test.added || skipped != test.skipped {
+			t.Errorf(`import #%d: got added %d skipped %d, want %d and %d`, i+1, added, skipped, test.added, test.skipped)
+		}
+	}
+	if len(ledger.Transactions) != 4 {
+		t.Errorf(`got %d transactions, want 4`, len(ledger.Transactions))
+	}
+	if ledger.Transactions[0].ID == ledger.Transactions[1].ID {
+		t.Errorf(`identical rows got the same fingerprint %s`, ledger.Transactions[0].ID)
+	}
+	if shares := ledger.Positions()[`AAPL`].Shares(); shares != 15 {
+		t.Errorf(`got %v shares, want 15`, shares)
+	}
+}
+
+func TestMappings(t *testing.T) {
+	tests := []struct {
+		mapping      string
+		statement    string
+		transactions []Transaction
+	}{
+		{
+			mapping: `schwab`,
+			statement: `"Transactions for account XXXX-1234"
+"Date","Action","Symbol","Description","Quantity","Price","Fees & Comm","Amount"
+"03/15/2024 as of 03/14/2024","Sell","AAPL","APPLE INC","5","$180.00","$1.00","$899.00"
+"02/01/2024","Qualified Dividend","AAPL","APPLE INC","","","","$2.40"
+"01/02/2024","Buy","aapl","APPLE INC","10","$150.00","","($1,500.00)"
+"01/01/2024","Journal","","CASH","","","","$100.00"
+"Transactions Total","","","","","","","$-500.60"
+`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Ticker: `AAPL`, Shares: 10, Price: 150, Amount: -1500},
+				{Date: `2024-02-01`, Type: Dividend, Ticker: `AAPL`, Amount: 2.4},
+				{Date: `2024-03-14`, Type: Sell, Ticker: `AAPL`, Shares: 5, Price: 180, Amount: 899, Fees: 1},
+			},
+		},
+		{
+			mapping: `fidelity`,
+			statement: bom + "Run Date,Action,Symbol,Quantity,Price ($),Commission ($),Fees ($),Amount ($)\n" +
+				"01/05/2024,YOU BOUGHT MICROSOFT CORP (MSFT),MSFT,3,370.5,0,0.02,-1111.52\n" +
+				"02/10/2024,YOU SOLD MICROSOFT CORP (MSFT),MSFT,-1,400,0,0.03,399.97\n",
+			transactions: []Transaction{
+				{Date: `2024-01-05`, Type: Buy, Ticker: `MSFT`, Shares: 3, Price: 370.5, Amount: -1111.52, Fees: 0.02},
+				{Date: `2024-02-10`, Type: Sell, Ticker: `MSFT`, Shares: 1, Price: 400, Amount: 399.97, Fees: 0.03},
+			},
+		},
+		{
+			mapping: `vanguard`,
+			statement: `Account Number,Trade Date,Settlement Date,Transaction Type,Transaction Description,Investment Name,Symbol,Shares,Share Price,Principal Amount,Commission Fees,Net Amount
+123,2024-06-10,2024-06-10,Stock split,Stock split,NVIDIA CORP,NVDA,90,0,0,0,0
+123,01/02/2024,01/04/2024,Buy,Buy,NVIDIA CORP,NVDA,10,480,-4800,0,-4800
+`,
+			transactions: []Transaction{
+				{Date: `2024-01-02`, Type: Buy, Ticker: `NVDA`, Shares: 10, Price: 480, Amount: -4800},
+				{Date: `2024-06-10`, Type: Split, Ticker: `NVDA`, Shares: 90},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mapping, func(t *testing.T) {
+			mapping, err := LookupMapping(test.mapping, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ledger := &Ledger{}
+			if _, _, err := ledger.Import(strings.NewReader(test.statement), mapping); err != nil {
+				t.Fatal(err)
+			}
+			for i := range ledger.Transactions {
+				ledger.Transactions[i].ID = ``
+			}
+			if !reflect.DeepEqual(ledger.Transactions, test.transactions) {
+				t.Errorf("got  %+v\nwant %+v", ledger.Transactions, test.transactions)
+			}
+		})
+	}
+}
+
+func TestLookupCustomMapping(t *testing.T) {
+	custom := []CSVMapping{{Name: `Schwab`, Date: `Trade Date`}}
+	if mapping, _ := LookupMapping(`schwab`, custom); mapping.Date != `Trade Date` {
+		t.Errorf(`custom mapping should take precedence over the built-in one`)
+	}
+	if _, err := LookupMapping(`unknown`, custom); err == nil {
+		t.Errorf(`unknown mapping should be reported`)
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]float64{
+		`$1,234.56`: 1234.56,
+		`(12.00)`:   -12,
+		` -3.5 `:    -3.5,
+		``:          0,
+		`n/a`:       0,
+	}
+	for value, want := range tests {
+		if got := parseAmount(value); got != want {
+			t.Errorf(`parseAmount(%q) = %v, want %v`, value, got, want)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+func equal(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -5,6 +5,7 @@
 package mop
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -24,6 +25,11 @@ type LineEditor struct {
 	quotes   *Quotes        // Pointer to Quotes.
 	regex    *regexp.Regexp // Regex to split comma-delimited input string.
 	hasError bool           // True if an error occurred during execute.
+	chart    *ChartView     // Chart view opened by the command, if any.
+	ticker   string         // Stock ticker of the row commands such as 'x' or 'a'.
+	line     int            // Screen row where the prompt is displayed.
+	recalled int            // How far back in the filter history the input has been recalled from, 0 if it hasn't.
+	draft    string         // Input typed before recalling the filter history.
 }
 
 // Returns new initialized LineEditor struct.
@@ -32,30 +38,55 @@ func NewLineEditor(screen *Screen, quotes *Quotes) *LineEditor {
 		screen: screen,
 		quotes: quotes,
 		regex:  regexp.MustCompile(`[,\s]+`),
+		line:   screen.PromptLine(),
 	}
 }
 
+// For sets the stock ticker the row commands apply to, i.e. remove ('x'),
+// alert ('a'), note ('N'), and tags ('#').
+func (editor *LineEditor) For(ticker string) *LineEditor {
+	editor.ticker = ticker
+	return editor
+}
+
+// At moves the prompt to the given screen row.
+func (editor *LineEditor) At(line int) *LineEditor {
+	editor.line = line
+	return editor
+}
+
 // Prompt displays a prompt in response to '+' or '-' commands. Unknown commands
-// are simply ignored. The prompt is displayed on the 3rd line (between the market
-// data and the stock quotes).
+// are simply ignored. The prompt is displayed on the line between the market
+// data and the stock quotes.
 func (editor *LineEditor) Prompt(command rune) *LineEditor {
 	filterPrompt := `Set filter: `
 	prompts := map[rune]string{
 		'+': `Add tickers: `, '-': `Remove tickers: `,
-		'f': filterPrompt,
+		'f': filterPrompt, '$': `Set position (ticker shares cost [yyyy-mm-dd]): `,
+		'n': `New watchlist: `, 'A': `Add alert (or -name to remove): `, 'c': `Chart ticker: `, 'X': `Delete watchlist ` + editor.quotes.profile.Name + `? (y/n) `,
+		'x': `Remove ` + editor.ticker + `? (y/n) `, 'a': `Alert on ` + editor.ticker + ` when: `, 'N': `Note on ` + editor.ticker + `: `,
+		'#': `Tags and @alias of ` + editor.ticker + `: `,
+		'o': `Show column (` + strings.Join(editor.hiddenColumns(), `, `) + `): `,
+		's': `Saved filter (` + strings.Join(append(editor.savedFilters(), `+name saves`, `-name removes`, `name>new renames`), `, `) + `): `,
 	}
 	if prompt, ok := prompts[command]; ok {
 		editor.prompt = prompt
 		editor.command = command
 
-		editor.screen.ClearLine(0, 4)
-		editor.screen.DrawLine(0, 4, `<white>`+editor.prompt+`</>`)
+		editor.screen.ClearLine(0, editor.line)
+		editor.screen.DrawLine(0, editor.line, `<white>`+editor.prompt+`</>`)
 		if command == 'f' {
 			editor.input = editor.quotes.profile.Filter
-			editor.screen.DrawLine(len(editor.prompt), 4, editor.input)
+		} else if annotation := editor.quotes.profile.Annotation(editor.ticker); command == 'N' && annotation != nil {
+			editor.input = annotation.Note
+		} else if command == '#' && annotation != nil {
+			editor.input = annotation.String()
+		}
+		if editor.input != `` {
+			editor.screen.DrawLine(len(editor.prompt), editor.line, editor.input)
 			editor.cursor = len(editor.input)
 		}
-		termbox.SetCursor(len(editor.prompt)+editor.cursor, 4)
+		termbox.SetCursor(len(editor.prompt)+editor.cursor, editor.line)
 		termbox.Flush()
 	}
 
@@ -89,6 +120,12 @@ func (editor *LineEditor) Handle(ev termbox.Event) bool {
 	case termbox.KeyCtrlA:
 		editor.jumpToBeginning()
 
+	case termbox.KeyArrowUp:
+		editor.recall(1)
+
+	case termbox.KeyArrowDown:
+		editor.recall(-1)
+
 	case termbox.KeyCtrlE:
 		editor.jumpToEnd()
 
@@ -114,7 +151,7 @@ func (editor *LineEditor) deletePreviousCharacter() *LineEditor {
 			// Remove last input character.
 			editor.input = editor.input[:len(editor.input)-1]
 		}
-		editor.screen.DrawLine(len(editor.prompt), 4, editor.input+` `) // Erase last character.
+		editor.screen.DrawLine(len(editor.prompt), editor.line, editor.input+` `) // Erase last character.
 		editor.moveLeft()
 	}
 
@@ -130,7 +167,7 @@ func (editor *LineEditor) insertCharacter(ch rune) *LineEditor {
 		// Append the character to the end of the input string.
 		editor.input += string(ch)
 	}
-	editor.screen.DrawLine(len(editor.prompt), 4, editor.input)
+	editor.screen.DrawLine(len(editor.prompt), editor.line, editor.input)
 	editor.moveRight()
 
 	return editor
@@ -140,7 +177,7 @@ func (editor *LineEditor) insertCharacter(ch rune) *LineEditor {
 func (editor *LineEditor) moveLeft() *LineEditor {
 	if editor.cursor > 0 {
 		editor.cursor--
-		termbox.SetCursor(len(editor.prompt)+editor.cursor, 4)
+		termbox.SetCursor(len(editor.prompt)+editor.cursor, editor.line)
 	}
 
 	return editor
@@ -150,7 +187,7 @@ func (editor *LineEditor) moveLeft() *LineEditor {
 func (editor *LineEditor) moveRight() *LineEditor {
 	if editor.cursor < len(editor.input) {
 		editor.cursor++
-		termbox.SetCursor(len(editor.prompt)+editor.cursor, 4)
+		termbox.SetCursor(len(editor.prompt)+editor.cursor, editor.line)
 	}
 
 	return editor
@@ -159,7 +196,7 @@ func (editor *LineEditor) moveRight() *LineEditor {
 // -----------------------------------------------------------------------------
 func (editor *LineEditor) jumpToBeginning() *LineEditor {
 	editor.cursor = 0
-	termbox.SetCursor(len(editor.prompt)+editor.cursor, 4)
+	termbox.SetCursor(len(editor.prompt)+editor.cursor, editor.line)
 
 	return editor
 }
@@ -167,11 +204,41 @@ func (editor *LineEditor) jumpToBeginning() *LineEditor {
 // -----------------------------------------------------------------------------
 func (editor *LineEditor) jumpToEnd() *LineEditor {
 	editor.cursor = len(editor.input)
-	termbox.SetCursor(len(editor.prompt)+editor.cursor, 4)
+	termbox.SetCursor(len(editor.prompt)+editor.cursor, editor.line)
 
 	return editor
 }
 
+// Replaces the input of the filter prompt with the filter set earlier in the
+// session, going back in the history if step is positive, and forward
+// otherwise. Going forward past the most recent filter restores the input
+// typed before recalling the history.
+// -----------------------------------------------------------------------------
+func (editor *LineEditor) recall(step int) *LineEditor {
+	history := editor.quotes.profile.filterHistory
+	if editor.command != 'f' || editor.recalled+step < 0 || editor.recalled+step > len(history) {
+		return editor
+	}
+	if editor.recalled == 0 {
+		editor.draft = editor.input
+	}
+	editor.recalled += step
+
+	previous := editor.input
+	if editor.recalled == 0 {
+		editor.input = editor.draft
+	} else {
+		editor.input = history[len(history)-editor.recalled]
+	}
+	if erase := len(previous) - len(editor.input); erase > 0 {
+		editor.screen.DrawLine(len(editor.prompt), editor.line, editor.input+strings.Repeat(` `, erase))
+	} else {
+		editor.screen.DrawLine(len(editor.prompt), editor.line, editor.input)
+	}
+
+	return editor.jumpToEnd()
+}
+
 // -----------------------------------------------------------------------------
 func (editor *LineEditor) execute() *LineEditor {
 	switch editor.command {
@@ -183,28 +250,129 @@ func (editor *LineEditor) execute() *LineEditor {
 			}
 		}
 	case '-':
-		tickers := editor.tokenize()
-		if len(tickers) > 0 {
-			before := len(editor.quotes.profile.Tickers)
-			if removed, _ := editor.quotes.RemoveTickers(tickers); removed > 0 {
-				editor.screen.Draw(editor.quotes)
-
-				// Clear the lines at the bottom of the list, if any.
-				after := before - removed
-				for i := before + 1; i > after; i-- {
-					editor.screen.ClearLine(0, i+4)
-				}
-			}
+		editor.remove(editor.tokenize())
+	case 'x':
+		if strings.ToLower(strings.TrimSpace(editor.input)) == `y` {
+			editor.remove([]string{editor.ticker})
 		}
 	case 'f':
 		if err := editor.quotes.profile.SetFilter(editor.input); err != nil {
-			editor.screen.DrawLine(0, 4, `<red>Error: `+err.Error()+`</>`)
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
 			editor.quotes.profile.SetFilter("")
 			editor.hasError = true
 			termbox.Flush()
 		} else {
+			editor.quotes.profile.rememberFilter(editor.input)
 			editor.screen.DrawOldQuotes(editor.quotes)
 		}
+	case 's':
+		var err error
+		input := strings.TrimSpace(editor.input)
+		if strings.HasPrefix(input, `+`) {
+			err = editor.quotes.profile.SaveFilter(input[1:], ``)
+		} else if strings.HasPrefix(input, `-`) {
+			err = editor.quotes.profile.RemoveFilter(input[1:])
+		} else if names := strings.SplitN(input, `>`, 2); len(names) == 2 {
+			err = editor.quotes.profile.RenameFilter(names[0], names[1])
+		} else if input != `` {
+			saved := editor.quotes.profile.SavedFilter(strings.TrimPrefix(input, `@`))
+			if saved == nil {
+				err = fmt.Errorf("no saved filter `%s`", input)
+			} else if err = editor.quotes.profile.SetFilter(`@` + saved.Name); err == nil {
+				editor.quotes.profile.rememberFilter(`@` + saved.Name)
+				editor.screen.DrawOldQuotes(editor.quotes)
+			}
+		}
+		if err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		}
+	case '$':
+		ticker, holding, err := ParseHolding(editor.input)
+		if err == nil {
+			err = editor.quotes.SetHolding(ticker, holding)
+		}
+		if err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		} else {
+			editor.screen.Draw(editor.quotes)
+		}
+	case 'n':
+		if err := editor.quotes.AddWatchlist(editor.input); err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		} else {
+			editor.screen.ScrollTop()
+			editor.screen.Clear().Draw(editor.quotes.market, editor.quotes)
+		}
+	case 'A':
+		var err error
+		input := strings.TrimSpace(editor.input)
+		if strings.HasPrefix(input, `-`) {
+			err = editor.quotes.Alerts().Remove(strings.TrimSpace(input[1:]))
+		} else if input != `` {
+			err = editor.quotes.Alerts().Add(input)
+		}
+		if err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		}
+	case 'X':
+		if strings.ToLower(strings.TrimSpace(editor.input)) == `y` {
+			if err := editor.quotes.RemoveWatchlist(); err != nil {
+				editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+				editor.hasError = true
+				termbox.Flush()
+			} else {
+				editor.screen.ScrollTop()
+				editor.screen.Clear().Draw(editor.quotes.market, editor.quotes)
+			}
+		}
+	case 'a':
+		if input := strings.TrimSpace(editor.input); input != `` {
+			expression := fmt.Sprintf(`ticker == '%s' && (%s)`, editor.ticker, input)
+			if err := editor.quotes.Alerts().Add(expression); err != nil {
+				editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+				editor.hasError = true
+				termbox.Flush()
+			}
+		}
+	case 'N':
+		if err := editor.quotes.profile.SetNote(editor.ticker, editor.input); err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		}
+	case '#':
+		if err := editor.quotes.profile.SetTags(editor.ticker, editor.input); err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		} else {
+			editor.screen.DrawOldQuotes(editor.quotes)
+		}
+	case 'o':
+		err := fmt.Errorf("no hidden column `%s`", strings.TrimSpace(editor.input))
+		for _, column := range editor.screen.layout.Hidden(editor.quotes.profile) {
+			if strings.EqualFold(column.title, strings.TrimSpace(editor.input)) || strings.EqualFold(column.name, strings.TrimSpace(editor.input)) {
+				err = editor.screen.layout.ShowColumn(editor.quotes.profile, column.name, true)
+				break
+			}
+		}
+		if err != nil {
+			editor.screen.DrawLine(0, editor.line, `<red>Error: `+err.Error()+`</>`)
+			editor.hasError = true
+			termbox.Flush()
+		}
+	case 'c':
+		if tickers := editor.tokenize(); tickers[0] != `` {
+			editor.chart = NewChartView(editor.screen, editor.quotes, tickers[0])
+		}
 	case 'F':
 		editor.quotes.profile.SetFilter("")
 		editor.screen.DrawOldQuotes(editor.quotes)
@@ -213,19 +381,59 @@ func (editor *LineEditor) execute() *LineEditor {
 	return editor
 }
 
+// -----------------------------------------------------------------------------
+func (editor *LineEditor) remove(tickers []string) {
+	if len(tickers) > 0 && tickers[0] != `` {
+		before := len(editor.quotes.profile.Tickers)
+		if removed, _ := editor.quotes.RemoveTickers(tickers); removed > 0 {
+			editor.screen.Draw(editor.quotes)
+
+			// Clear the lines at the bottom of the list, if any.
+			after := before - removed
+			for i := before + 1; i > after; i-- {
+				editor.screen.ClearLine(0, i+editor.screen.headerLine-1)
+			}
+		}
+	}
+}
+
 // -----------------------------------------------------------------------------
 func (editor *LineEditor) done() bool {
 	if editor == nil {
 		return false
 	}
-	if !editor.hasError {
-		editor.screen.ClearLine(0, 4)
+	if !editor.hasError && editor.chart == nil {
+		editor.screen.ClearLine(0, editor.line)
 	}
 	termbox.HideCursor()
 
 	return true
 }
 
+// Chart returns the chart view opened by the command, or nil if there is
+// none. The chart view takes over the keyboard once the editor is done.
+func (editor *LineEditor) Chart() *ChartView {
+	return editor.chart
+}
+
+// Returns the titles of the columns that are not shown.
+func (editor *LineEditor) hiddenColumns() []string {
+	var titles []string
+	for _, column := range editor.screen.layout.Hidden(editor.quotes.profile) {
+		titles = append(titles, column.title)
+	}
+	return titles
+}
+
+// Returns the names of the saved filters.
+func (editor *LineEditor) savedFilters() []string {
+	var names []string
+	for _, saved := range editor.quotes.profile.SavedFilters {
+		names = append(names, saved.Name)
+	}
+	return names
+}
+
 // Split by whitespace/comma to convert a string to array of tickers. Make sure
 // the string is trimmed to avoid empty tickers in the array.
 func (editor *LineEditor) tokenize() []string {
@@ -4,34 +4,58 @@
 
 package mop
 
-// Market stores current market information displayed in the top three lines of
-// the screen. The market data is fetched and parsed from the HTML page above.
+import "time"
+
+// Market summary display styles.
+const (
+	StylePoints  = `points`  // Change, change percent, and price, ex. Dow.
+	StylePercent = `percent` // Price and change percent, ex. Oil.
+	StyleChange  = `change`  // Price and change, ex. 10-year yield.
+)
+
+// MarketItem describes one symbol of the market summary strip shown at the
+// top of the screen.
+type MarketItem struct {
+	Symbol string // Yahoo symbol, ex. ^GSPC.
+	Label  string // Label shown in front of the quote, ex. S&P 500.
+	Style  string // One of StylePoints (default), StylePercent, or StyleChange.
+	Prefix string // Shown in front of the price, ex. $.
+	Break  bool   // Start new line with this item.
+}
+
+// defaultMarketStrip lists the market summary symbols shown unless the
+// profile says otherwise.
+var defaultMarketStrip = []MarketItem{
+	{Symbol: `^DJI`, Label: `Dow`},
+	{Symbol: `^GSPC`, Label: `S&P 500`},
+	{Symbol: `^IXIC`, Label: `NASDAQ`},
+	{Symbol: `^N225`, Label: `Tokyo`, Break: true},
+	{Symbol: `^HSI`, Label: `HK`},
+	{Symbol: `^FTSE`, Label: `London`},
+	{Symbol: `^GDAXI`, Label: `Frankfurt`},
+	{Symbol: `^TNX`, Label: `10-Year Yield`, Style: StyleChange, Break: true},
+	{Symbol: `EUR=X`, Label: `Euro`, Style: StylePercent, Prefix: `$`},
+	{Symbol: `JPY=X`, Label: `Yen`, Style: StylePercent, Prefix: `¥`},
+	{Symbol: `CL=F`, Label: `Oil`, Style: StylePercent, Prefix: `$`},
+	{Symbol: `GC=F`, Label: `Gold`, Style: StylePercent, Prefix: `$`},
+}
+
+// Market stores current market information displayed in the top lines of
+// the screen. The symbols come from the market summary strip of the profile.
 type Market struct {
 	*MarketData
 	errors   string        // Error(s), if any.
+	profile  *Profile      // Pointer to Profile.
 	provider StockProvider // Provider to fetch market data.
 }
 
 // Returns new initialized Market struct.
-func NewMarket(provider StockProvider) *Market {
+func NewMarket(profile *Profile, provider StockProvider) *Market {
 	market := &Market{
-		MarketData: &MarketData{
-			IsClosed:  false,
-			Dow:       make(map[string]string),
-			Nasdaq:    make(map[string]string),
-			Sp500:     make(map[string]string),
-			Tokyo:     make(map[string]string),
-			HongKong:  make(map[string]string),
-			London:    make(map[string]string),
-			Frankfurt: make(map[string]string),
-			Yield:     make(map[string]string),
-			Oil:       make(map[string]string),
-			Yen:       make(map[string]string),
-			Euro:      make(map[string]string),
-			Gold:      make(map[string]string),
-		},
-		errors:   "",
-		provider: provider,
+		MarketData: &MarketData{},
+		errors:     "",
+		profile:    profile,
+		provider:   provider,
 	}
 
 	return market
@@ -42,15 +66,33 @@ func NewMarket(provider StockProvider) *Market {
 func (market *Market) Fetch() (self *Market) {
 	self = market
 	
-	marketData, err := market.provider.FetchMarket()
+	marketData, err := market.provider.FetchMarket(market.profile.MarketSymbols())
+	market.update(marketData, err)
+
+	return market
+}
+
+// update replaces the market data with the one fetched from the provider,
+// or sets the error.
+func (market *Market) update(marketData *MarketData, err error) {
 	if err != nil {
 		market.errors = err.Error()
 	} else {
 		market.errors = ""
 		market.MarketData = marketData
 	}
+}
 
-	return market
+// Refresh returns how long to wait before fetching market data again: the
+// given refresh interval while U.S. markets are open, or closedRefresh or
+// till the next trading session, whichever comes first, when they are
+// closed. Negative closedRefresh waits for the next session.
+func (market *Market) Refresh(refresh, closedRefresh time.Duration) time.Duration {
+	now := time.Now()
+	if market.MarketData == nil || !market.IsClosed || nyse.Session(now) != SessionClosed {
+		return refresh
+	}
+	return closedWait(closedRefresh, now, nyse.NextSession(now))
 }
 
 // Ok returns two values: 1) boolean indicating whether the error has occurred,
@@ -0,0 +1,175 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the request latency histogram buckets in seconds.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects request counters and latency histograms of the data
+// provider, and writes them in Prometheus text format.
+type Metrics struct {
+	sync.Mutex
+	latency        map[string]*histogram // Request latency by endpoint.
+	failures       map[string]int64      // Failed requests by endpoint.
+	crumbRefreshes int64                 // Number of times the crumb has been fetched.
+	chunks         int64                 // Total number of quote request chunks.
+	lastChunks     int                   // Number of chunks of the last quote request.
+}
+
+// Instrumented is implemented by data providers that collect metrics.
+type Instrumented interface {
+	Metrics() *Metrics
+}
+
+// histogram is cumulative histogram with the latencyBuckets bounds.
+type histogram struct {
+	counts []int64 // Number of observations less or equal to each bound.
+	count  int64   // Total number of observations.
+	sum    float64 // Sum of all observations.
+}
+
+// Returns new empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		latency:  make(map[string]*histogram),
+		failures: make(map[string]int64),
+	}
+}
+
+// WriteTo writes the metrics in Prometheus text format.
+func (metrics *Metrics) WriteTo(w io.Writer) (int64, error) {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	str := "# HELP mop_yahoo_request_duration_seconds Latency of the requests to Yahoo Finance.\n" +
+		"# TYPE mop_yahoo_request_duration_seconds histogram\n"
+	for _, endpoint := range metrics.endpoints() {
+		h := metrics.latency[endpoint]
+		for i, bound := range latencyBuckets {
+			str += fmt.Sprintf("mop_yahoo_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n",
+				endpoint, strconv.FormatFloat(bound, 'f', -1, 64), h.counts[i])
+		}
+		str += fmt.Sprintf("mop_yahoo_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", endpoint, h.count)
+		str += fmt.Sprintf("mop_yahoo_request_duration_seconds_sum{endpoint=%q} %g\n", endpoint, h.sum)
+		str += fmt.Sprintf("mop_yahoo_request_duration_seconds_count{endpoint=%q} %d\n", endpoint, h.count)
+	}
+
+	str += "# HELP mop_yahoo_request_failures_total Failed requests to Yahoo Finance.\n" +
+		"# TYPE mop_yahoo_request_failures_total counter\n"
+	for _, endpoint := range metrics.endpoints() {
+		str += fmt.Sprintf("mop_yahoo_request_failures_total{endpoint=%q} %d\n", endpoint, metrics.failures[endpoint])
+	}
+
+	str += "# HELP mop_yahoo_crumb_refreshes_total Number of times the crumb has been fetched.\n" +
+		"# TYPE mop_yahoo_crumb_refreshes_total counter\n" +
+		fmt.Sprintf("mop_yahoo_crumb_refreshes_total %d\n", metrics.crumbRefreshes)
+
+	str += "# HELP mop_yahoo_quote_chunks_total Number of chunks the quote requests have been split into.\n" +
+		"# TYPE mop_yahoo_quote_chunks_total counter\n" +
+		fmt.Sprintf("mop_yahoo_quote_chunks_total %d\n", metrics.chunks)
+
+	str += "# HELP mop_yahoo_quote_chunks Number of chunks of the last quote request.\n" +
+		"# TYPE mop_yahoo_quote_chunks gauge\n" +
+		fmt.Sprintf("mop_yahoo_quote_chunks %d\n", metrics.lastChunks)
+
+	n, err := io.WriteString(w, str)
+	return int64(n), err
+}
+
+// -----------------------------------------------------------------------------
+func (metrics *Metrics) observe(endpoint string, start time.Time, err *error) {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	h, ok := metrics.latency[endpoint]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(latencyBuckets))}
+		metrics.latency[endpoint] = h
+	}
+	seconds := time.Since(start).Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+
+	if *err != nil {
+		metrics.failures[endpoint]++
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (metrics *Metrics) crumbRefreshed() {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	metrics.crumbRefreshes++
+}
+
+// -----------------------------------------------------------------------------
+func (metrics *Metrics) chunked(count int) {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	metrics.chunks += int64(count)
+	metrics.lastChunks = count
+}
+
+// -----------------------------------------------------------------------------
+func (metrics *Metrics) endpoints() []string {
+	endpoints := make([]string, 0, len(metrics.latency))
+	for endpoint := range metrics.latency {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	return endpoints
+}
+
+// writeStockMetrics writes per-ticker gauges of the given stock quotes in
+// Prometheus text format. The values that are not available are skipped.
+func writeStockMetrics(w io.Writer, stocks []Stock) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(*Stock) NullFloat
+	}{
+		{`mop_stock_last_price`, `Last trade price.`, func(s *Stock) NullFloat { return s.LastTrade }},
+		{`mop_stock_change_percent`, `Change percent since previous close.`, func(s *Stock) NullFloat { return s.ChangePct }},
+		{`mop_stock_volume`, `Trading volume.`, func(s *Stock) NullFloat { return s.Volume.Float() }},
+		{`mop_stock_market_cap`, `Market capitalization.`, func(s *Stock) NullFloat { return s.MarketCap.Float() }},
+	}
+
+	str := ``
+	for _, gauge := range gauges {
+		str += fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name)
+		for i := range stocks {
+			if value := gauge.value(&stocks[i]); value.Valid {
+				str += fmt.Sprintf("%s{ticker=\"%s\",currency=\"%s\"} %g\n",
+					gauge.name, label(stocks[i].Ticker), label(stocks[i].Currency), value.Value)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, str)
+	return err
+}
+
+// -----------------------------------------------------------------------------
+func label(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
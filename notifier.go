@@ -0,0 +1,117 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// BellNotifier rings the terminal bell.
+type BellNotifier struct{}
+
+// Notify writes the BEL character to the terminal. Stderr is used so that
+// the bell doesn't end up in the output of the one-shot mode.
+func (bell *BellNotifier) Notify(alert *Alert) error {
+	_, err := os.Stderr.WriteString("\a")
+	return err
+}
+
+// FlashNotifier asks the main loop to flash the screen. Since the alerts get
+// evaluated in the background the flash requests are passed through the
+// channel.
+type FlashNotifier struct {
+	C chan *Alert // Flash requests.
+}
+
+// Returns new FlashNotifier with buffered channel.
+func NewFlashNotifier() *FlashNotifier {
+	return &FlashNotifier{C: make(chan *Alert, 16)}
+}
+
+// Notify sends the flash request unless there are too many pending already.
+func (flash *FlashNotifier) Notify(alert *Alert) error {
+	select {
+	case flash.C <- alert:
+	default:
+	}
+	return nil
+}
+
+// CommandNotifier runs user-configured shell command. The alert details are
+// passed in MOP_RULE, MOP_MESSAGE, MOP_TICKER, MOP_LAST, MOP_CHANGE, and
+// MOP_CHANGE_PERCENT environment variables.
+type CommandNotifier struct {
+	Command string // Shell command to run.
+}
+
+// Notify starts the command without waiting for it to complete.
+func (notifier *CommandNotifier) Notify(alert *Alert) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == `windows` {
+		cmd = exec.Command(`cmd`, `/C`, notifier.Command)
+	} else {
+		cmd = exec.Command(`sh`, `-c`, notifier.Command)
+	}
+	cmd.Env = append(os.Environ(),
+		`MOP_RULE=`+alert.Rule,
+		`MOP_MESSAGE=`+alert.Message,
+		`MOP_TICKER=`+alert.Ticker,
+		`MOP_LAST=`+envFloat(alert.Last),
+		`MOP_CHANGE=`+envFloat(alert.Change),
+		`MOP_CHANGE_PERCENT=`+envFloat(alert.ChangePct),
+	)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+
+	return nil
+}
+
+// WebhookNotifier POSTs the alert as JSON to the given URL.
+type WebhookNotifier struct {
+	URL    string       // Webhook URL.
+	client *http.Client // HTTP client with the timeout.
+}
+
+// Returns new WebhookNotifier for the given URL.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Notify sends the alert and expects 2xx response.
+func (webhook *WebhookNotifier) Notify(alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	response, err := webhook.client.Post(webhook.URL, `application/json`, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", response.Status)
+	}
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+func envFloat(value NullFloat) string {
+	if !value.Valid {
+		return ``
+	}
+	return strconv.FormatFloat(value.Value, 'f', -1, 64)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		status int
+		ok     bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusNoContent, true},
+		{http.StatusBadRequest, false},
+		{http.StatusInternalServerError, false},
+	}
+
+	for _, test := range tests {
+		var received Alert
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost || r.Header.Get(`Content-Type`) != `application/json` {
+				t.Errorf(`got %s request with %q content type`, r.Method, r.Header.Get(`Content-Type`))
+			}
+			if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+				t.Errorf(`can't decode the alert: %v`, err)
+			}
+			w.WriteHeader(test.status)
+		}))
+
+		err := NewWebhookNotifier(server.URL).Notify(&Alert{Rule: `last > 200`, Ticker: `AAPL`, Last: Float(201)})
+		if (err == nil) != test.ok {
+			t.Errorf(`status %d: got error %v, want ok %v`, test.status, err, test.ok)
+		}
+		if received.Rule != `last > 200` || received.Ticker != `AAPL` || received.Last != Float(201) {
+			t.Errorf(`status %d: got alert %+v`, test.status, received)
+		}
+		server.Close()
+	}
+}
+
+func TestWebhookNotifierUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	if err := NewWebhookNotifier(server.URL).Notify(&Alert{}); err == nil {
+		t.Errorf(`unreachable webhook should be reported`)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Output formats supported by the one-shot mode.
+const (
+	FormatTable = `table`
+	FormatJSON  = `json`
+	FormatCSV   = `csv`
+)
+
+// Print writes market data and stock quotes to the writer in the given format
+// without using the screen. The stock quotes are filtered, sorted, and grouped
+// the same way they are displayed on the screen. Market data is omitted if it
+// could not be fetched.
+func (layout *Layout) Print(writer io.Writer, format string, market *Market, quotes *Quotes) error {
+	switch format {
+	case FormatTable:
+		return layout.printTable(writer, market, quotes)
+	case FormatJSON:
+		return layout.printJSON(writer, market, quotes)
+	case FormatCSV:
+		return layout.printCSV(writer, quotes)
+	}
+	return fmt.Errorf("unknown output format `%s`", format)
+}
+
+// -----------------------------------------------------------------------------
+func (layout *Layout) printTable(writer io.Writer, market *Market, quotes *Quotes) error {
+	str := layout.Header(quotes.profile) + "\n"
+	if ok, _ := market.Ok(); ok {
+		str = layout.Market(market) + "\n\n" + str
+	}
+	for _, stock := range layout.prettify(quotes) {
+		if stock[`Group`] != `` {
+			str += stock[`Group`] + "\n"
+			continue
+		}
+		str += stock[`Row`] + "\n"
+		if stock[`Extended`] != `` {
+			str += stock[`Extended`] + "\n"
+		}
+	}
+	if totals := layout.totals(quotes); len(totals) > 0 {
+		str += "\n" + strings.Join(totals, "\n") + "\n"
+	}
+
+	// Drop the markup and the trailing blanks.
+	markup := NewMarkup(quotes.profile)
+	lines := strings.Split(str, "\n")
+	for i, line := range lines {
+		plain := ``
+		for _, token := range markup.Tokenize(line) {
+			if !markup.IsTag(token) {
+				plain += token
+			}
+		}
+		lines[i] = strings.TrimRight(plain, ` `)
+	}
+
+	_, err := io.WriteString(writer, strings.Join(lines, "\n"))
+	return err
+}
+
+// -----------------------------------------------------------------------------
+func (layout *Layout) printJSON(writer io.Writer, market *Market, quotes *Quotes) error {
+	stocks := layout.arrange(quotes)
+	output := struct {
+		Market *MarketData `json:"market"`
+		Stocks []Stock     `json:"stocks"`
+		Totals []Totals    `json:"totals,omitempty"`
+	}{
+		nil,
+		stocks,
+		totals(stocks), // Same as the stocks, only the positions that have passed the filter.
+	}
+	if ok, _ := market.Ok(); ok {
+		output.Market = market.MarketData
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent(``, `  `)
+	return encoder.Encode(output)
+}
+
+// -----------------------------------------------------------------------------
+func (layout *Layout) printCSV(writer io.Writer, quotes *Quotes) error {
+	columns := layout.visible(quotes.profile)
+	records := [][]string{{}}
+	for _, column := range columns {
+		records[0] = append(records[0], column.title)
+	}
+	records[0] = append(records[0], `Currency`)
+
+	// Unlike the table the values are not formatted: the numbers are written
+	// as is, and the values that are not available are left blank.
+	for _, stock := range layout.arrange(quotes) {
+		var record []string
+		for _, column := range columns {
+			value := stock.value(column.name)
+			if column.name == `Sparkline` {
+				value = stock.trend() // Intraday change percent rather than the prices.
+			}
+			record = append(record, raw(value))
+		}
+		records = append(records, append(record, stock.Currency))
+	}
+
+	return csv.NewWriter(writer).WriteAll(records)
+}
+
+// -----------------------------------------------------------------------------
+func raw(value interface{}) string {
+	switch value := value.(type) {
+	case NullFloat:
+		if value.Valid {
+			return strconv.FormatFloat(value.Value, 'f', -1, 64)
+		}
+	case NullInt:
+		if value.Valid {
+			return strconv.FormatInt(value.Value, 10)
+		}
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+	default:
+		return fmt.Sprint(value)
+	}
+	return ``
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrintJSONTotals(t *testing.T) {
+	profile := &Profile{Watchlist: &Watchlist{}, filename: filepath.Join(t.TempDir(), `moprc`)}
+	if err := profile.SetFilter(`ticker != 'MSFT'`); err != nil {
+		t.Fatal(err)
+	}
+	position := func(ticker, currency string, value, cost float64) Stock {
+		return Stock{Ticker: ticker, Currency: currency, Shares: Float(1), Value: Float(value), Cost: Float(cost), DayPnl: Float(1), TotalPnl: Float(value - cost)}
+	}
+	quotes := &Quotes{profile: profile, stocks: []Stock{
+		position(`AAPL`, `USD`, 200, 150),
+		position(`MSFT`, `USD`, 400, 300),
+		position(`ASML.AS`, `EUR`, 600, 700),
+		{Ticker: `NVDA`, Currency: `USD`},
+	}}
+
+	var buffer bytes.Buffer
+	if err := NewLayout().Print(&buffer, FormatJSON, &Market{errors: `offline`}, quotes); err != nil {
+		t.Fatal(err)
+	}
+	var output struct {
+		Stocks []Stock
+		Totals []Totals
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &output); err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Stocks) != 3 {
+		t.Errorf(`got %d stocks, want 3`, len(output.Stocks))
+	}
+	want := []Totals{
+		{Currency: `EUR`, Value: 600, Cost: 700, DayPnl: 1, TotalPnl: -100},
+		{Currency: `USD`, Value: 200, Cost: 150, DayPnl: 1, TotalPnl: 50},
+	}
+	if !reflect.DeepEqual(output.Totals, want) {
+		t.Errorf("got  %+v\nwant %+v", output.Totals, want)
+	}
+}
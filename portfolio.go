@@ -0,0 +1,118 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Holding describes the position in the particular stock as entered by the
+// user.
+type Holding struct {
+	Shares    float64 // Number of shares held.
+	Cost      float64 // Average cost per share.
+	Purchased string  `json:",omitempty"` // Optional purchase date (YYYY-MM-DD).
+}
+
+// Totals stores aggregated value and profit and loss of all the positions
+// held in the particular currency.
+type Totals struct {
+	Currency string  // Currency code of the positions.
+	Value    float64 // Current value of all the positions.
+	Cost     float64 // Total cost basis of all the positions.
+	DayPnl   float64 // Today's profit or loss.
+	TotalPnl float64 // Total profit or loss.
+}
+
+// ParseHolding converts user input such as `AAPL 10 150.25 2024-03-15`
+// to the ticker and the holding. Zero shares mean the position is closed.
+func ParseHolding(input string) (string, *Holding, error) {
+	fields := strings.Fields(strings.Replace(input, `,`, ` `, -1))
+	if len(fields) < 2 || len(fields) > 4 {
+		return ``, nil, fmt.Errorf("expected: ticker shares cost [yyyy-mm-dd]")
+	}
+
+	ticker := strings.ToUpper(fields[0])
+	holding := &Holding{}
+	shares, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || shares < 0 {
+		return ``, nil, fmt.Errorf("invalid number of shares `%s`", fields[1])
+	}
+	holding.Shares = shares
+	if shares == 0 {
+		return ticker, holding, nil
+	}
+	if len(fields) < 3 {
+		return ``, nil, fmt.Errorf("expected: ticker shares cost [yyyy-mm-dd]")
+	}
+	cost, err := strconv.ParseFloat(strings.TrimPrefix(fields[2], `$`), 64)
+	if err != nil || cost < 0 {
+		return ``, nil, fmt.Errorf("invalid cost `%s`", fields[2])
+	}
+	holding.Cost = cost
+	if len(fields) == 4 {
+		if _, err := time.Parse(`2006-01-02`, fields[3]); err != nil {
+			return ``, nil, fmt.Errorf("invalid purchase date `%s`", fields[3])
+		}
+		holding.Purchased = fields[3]
+	}
+
+	return ticker, holding, nil
+}
+
+// valuate calculates value and profit and loss of the stock position, if any.
+func (stock *Stock) valuate(holding *Holding) {
+	stock.Shares, stock.Value, stock.Cost = NullFloat{}, NullFloat{}, NullFloat{}
+	stock.DayPnl, stock.TotalPnl, stock.TotalPnlPct = NullFloat{}, NullFloat{}, NullFloat{}
+	if holding == nil || holding.Shares == 0 {
+		return
+	}
+
+	stock.Shares = Float(holding.Shares)
+	stock.Cost = Float(holding.Shares * holding.Cost)
+	if stock.LastTrade.Valid {
+		stock.Value = Float(holding.Shares * stock.LastTrade.Value)
+		stock.TotalPnl = Float(stock.Value.Value - stock.Cost.Value)
+		if stock.Cost.Value != 0 {
+			stock.TotalPnlPct = Float(stock.TotalPnl.Value / stock.Cost.Value * 100)
+		}
+	}
+	if stock.Change.Valid {
+		stock.DayPnl = Float(holding.Shares * stock.Change.Value)
+	}
+}
+
+// totals adds up the positions in the given stocks grouping them by the
+// currency.
+func totals(stocks []Stock) []Totals {
+	byCurrency := map[string]*Totals{}
+
+	for _, stock := range stocks {
+		if !stock.Shares.Valid || !stock.Value.Valid {
+			continue
+		}
+		total, ok := byCurrency[stock.Currency]
+		if !ok {
+			total = &Totals{Currency: stock.Currency}
+			byCurrency[stock.Currency] = total
+		}
+		total.Value += stock.Value.Value
+		total.Cost += stock.Cost.Value
+		total.DayPnl += stock.DayPnl.Value
+		total.TotalPnl += stock.TotalPnl.Value
+	}
+
+	list := make([]Totals, 0, len(byCurrency))
+	for _, total := range byCurrency {
+		list = append(list, *total)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+
+	return list
+}
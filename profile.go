@@ -23,20 +23,41 @@ const (
 	defaultColor       = "lightgray"
 )
 
+// Ways to show pre-market and after hours trading.
+const (
+	ExtendedOff     = `off`     // Extended hours columns only.
+	ExtendedRow     = `row`     // Extended hours price and change below the stock quote.
+	ExtendedReplace = `replace` // Extended hours price and change instead of the regular ones.
+)
+
 // Profile manages Mop program settings as defined by user (ex. list of
 // stock tickers). The settings are serialized using JSON and saved in
 // the ~/.moprc file.
 type Profile struct {
-	Tickers       []string // List of stock tickers to display.
-	MarketRefresh int      // Time interval to refresh market data.
-	QuotesRefresh int      // Time interval to refresh stock quotes.
-	SortColumn    int      // Column number by which we sort stock quotes.
-	Ascending     bool     // True when sort order is ascending.
-	Grouped       bool     // True when stocks are grouped by advancing/declining.
-	Filter        string   // Filter in human form
-	UpDownJump    int      // Number of lines to go up/down when scrolling.
-	RowShading    bool     // Should alternate rows be shaded?
-	Colors        struct { // User defined colors
+	*Watchlist      `json:"-"`             // Active watchlist.
+	Watchlists      []*Watchlist           // Named lists of stock tickers.
+	ActiveWatchlist int                    // Index of the active watchlist.
+	MarketRefresh   int                    // Time interval to refresh market data.
+	QuotesRefresh   int                    // Time interval to refresh stock quotes.
+	ClosedRefresh   int                    // Time interval to refresh when the markets are closed, negative to wait till they open.
+	ChartRefresh    int                    // Time interval to refresh price history charts.
+	Holdings        map[string]*Holding    // Positions held keyed by stock ticker.
+	Ledger          string                 // Path to the transaction ledger, defaults to profile path + `.ledger`.
+	Mappings        []CSVMapping           // Custom mappings of broker CSV exports.
+	MarketStrip     []MarketItem           // Symbols of the market summary shown at the top of the screen.
+	Columns         []ColumnConfig         // Order, widths, and visibility of the stock quotes columns.
+	ComputedColumns []*ComputedColumn      // Columns calculated from the stock quotes.
+	Alerts          []*AlertRule           // Alert rules checked whenever stock quotes get fetched.
+	AlertState      map[string]*AlertState // State of alert rules keyed by rule name and ticker.
+	Annotations     map[string]*Annotation // Notes about the stocks keyed by ticker.
+	SavedFilters    []*SavedFilter         // Named filters to apply with the s key or to refer to as @name.
+	Notifiers       struct {               // Alert notifiers settings.
+		Command string // Shell command to run when the alert fires.
+		Webhook string // URL to POST fired alerts to.
+	}
+	UpDownJump int      // Number of lines to go up/down when scrolling.
+	RowShading bool     // Should alternate rows be shaded?
+	Colors     struct { // User defined colors
 		Gain       string
 		Loss       string
 		Tag        string
@@ -48,10 +69,11 @@ type Profile struct {
 		Custom2    int
 		Custom3    int
 	}
-	ShowTimestamp    bool                           // Show or hide current time in the top right of the screen
-	filterExpression *govaluate.EvaluableExpression // The filter as a govaluate expression
-	selectedColumn   int                            // Stores selected column number when the column editor is active.
-	filename         string                         // Path to the file in which the configuration is stored
+	ShowTimestamp  bool     // Show or hide current time in the top right of the screen
+	ExtendedHours  string   // How to show pre-market and after hours trading: off, row, or replace.
+	selectedColumn int      // Stores selected column number when the column editor is active.
+	filterHistory  []string // Filters set during the session, oldest first.
+	filename       string   // Path to the file in which the configuration is stored
 }
 
 // Checks if a string represents a supported color or not.
@@ -99,7 +121,7 @@ func NewProfile(filename string) (*Profile, error) {
 			InitColor(&profile.Colors.Default, defaultColor)
 			InitColor(&profile.Colors.RowShading, defaultColor)
 
-			err = profile.SetFilter(profile.Filter)
+			err = profile.initWatchlists(data)
 		}
 	} else {
 		profile.InitDefaultProfile()
@@ -110,20 +132,40 @@ func NewProfile(filename string) (*Profile, error) {
 	if profile.UpDownJump < 1 {
 		profile.UpDownJump = 10
 	}
+	if profile.MarketRefresh < 1 {
+		profile.MarketRefresh = 600
+	}
+	if profile.QuotesRefresh < 1 {
+		profile.QuotesRefresh = 600
+	}
+	if profile.ClosedRefresh == 0 {
+		profile.ClosedRefresh = 3600
+	}
+	if profile.ChartRefresh < 1 {
+		profile.ChartRefresh = 300
+	}
+	if len(profile.MarketStrip) == 0 {
+		profile.MarketStrip = append([]MarketItem(nil), defaultMarketStrip...)
+	}
+	for _, computed := range profile.ComputedColumns {
+		computed.compile() // The errors get reported along with the quotes.
+	}
 
 	return profile, err
 }
 
 // Initializes a profile with the default values
 func (profile *Profile) InitDefaultProfile() {
-	profile.MarketRefresh = 600 // Market data gets fetched every 600s (1 time per 5 minutes).
-	profile.QuotesRefresh = 600 // Stock quotes get updated every 600s (1 time per 5 minutes).
-	profile.Grouped = false     // Stock quotes are *not* grouped by advancing/declining.
-	profile.Tickers = []string{`AAPL`, `C`, `GOOG`, `IBM`, `KO`, `ORCL`, `V`}
-	profile.SortColumn = 0   // Stock quotes are sorted by ticker name.
-	profile.Ascending = true // A to Z.
-	profile.Filter = ""
+	profile.MarketRefresh = 600                                // Market data gets fetched every 600s (1 time per 5 minutes).
+	profile.QuotesRefresh = 600                                // Stock quotes get updated every 600s (1 time per 5 minutes).
+	profile.ClosedRefresh = 3600                               // Once an hour when the markets are closed.
+	profile.ChartRefresh = 300                                 // Price history charts get updated every 5 minutes.
+	profile.Watchlists = []*Watchlist{NewWatchlist(`Default`)} // Stock quotes are sorted by ticker name A to Z.
+	profile.Watchlists[0].Tickers = []string{`AAPL`, `C`, `GOOG`, `IBM`, `KO`, `ORCL`, `V`}
+	profile.ActiveWatchlist = 0
+	profile.Watchlist = profile.Watchlists[0]
 	profile.UpDownJump = 10
+	profile.MarketStrip = append([]MarketItem(nil), defaultMarketStrip...)
 	profile.Colors.Gain = defaultGainColor
 	profile.Colors.Loss = defaultLossColor
 	profile.Colors.Tag = defaultTagColor
@@ -133,9 +175,59 @@ func (profile *Profile) InitDefaultProfile() {
 	profile.Colors.RowShading = defaultColor
 	profile.RowShading = false
 	profile.ShowTimestamp = false
+	profile.ExtendedHours = ExtendedOff
 	profile.Save()
 }
 
+// initWatchlists makes sure there is at least one watchlist, compiles all
+// the watchlist filters, and activates the watchlist the user was looking
+// at last time. Profiles saved before watchlists were introduced keep the
+// tickers, sort order, grouping, and filter at the top level; those get
+// moved to the default watchlist.
+func (profile *Profile) initWatchlists(data []byte) error {
+	if len(profile.Watchlists) == 0 {
+		legacy := NewWatchlist(`Default`)
+		if err := json.Unmarshal(data, legacy); err != nil {
+			return err
+		}
+		profile.Watchlists = []*Watchlist{legacy}
+	}
+	if profile.ActiveWatchlist < 0 || profile.ActiveWatchlist >= len(profile.Watchlists) {
+		profile.ActiveWatchlist = 0
+	}
+
+	var err error
+	for i, watchlist := range profile.Watchlists {
+		profile.Watchlist = watchlist
+		if e := profile.SetFilter(watchlist.Filter); e != nil && i == profile.ActiveWatchlist {
+			err = e
+		}
+	}
+	profile.Watchlist = profile.Watchlists[profile.ActiveWatchlist]
+
+	return err
+}
+
+// MarketSymbols returns the symbols of the market summary strip.
+func (profile *Profile) MarketSymbols() []string {
+	symbols := make([]string, len(profile.MarketStrip))
+	for i, item := range profile.MarketStrip {
+		symbols[i] = item.Symbol
+	}
+	return symbols
+}
+
+// MarketLines returns the number of lines taken by the market summary strip.
+func (profile *Profile) MarketLines() int {
+	lines := 1
+	for i, item := range profile.MarketStrip {
+		if item.Break && i > 0 {
+			lines++
+		}
+	}
+	return lines
+}
+
 // Initializes a color to the given string, or to the default value if the given
 // string does not represent a supported color.
 func InitColor(color *string, defaultValue string) {
@@ -203,29 +295,110 @@ func (profile *Profile) RemoveTickers(tickers []string) (removed int, err error)
 	return
 }
 
+// SetHolding saves the position in the given stock. Zero shares remove the
+// position.
+func (profile *Profile) SetHolding(ticker string, holding *Holding) error {
+	if holding == nil || holding.Shares == 0 {
+		delete(profile.Holdings, ticker)
+	} else {
+		if profile.Holdings == nil {
+			profile.Holdings = make(map[string]*Holding)
+		}
+		profile.Holdings[ticker] = holding
+	}
+	return profile.Save()
+}
+
+// LedgerFilename returns the path to the file in which the transaction
+// ledger is stored.
+func (profile *Profile) LedgerFilename() string {
+	if profile.Ledger != `` {
+		return profile.Ledger
+	}
+	return profile.filename + `.ledger`
+}
+
+// SyncHoldings replaces the holdings of all the tickers found in the ledger
+// with the positions derived from it. Holdings of the tickers that have
+// never been traded through the ledger are left intact.
+func (profile *Profile) SyncHoldings(ledger *Ledger) error {
+	if profile.Holdings == nil {
+		profile.Holdings = make(map[string]*Holding)
+	}
+	var tickers []string
+	for ticker, position := range ledger.Positions() {
+		if holding := position.Holding(); holding != nil {
+			profile.Holdings[ticker] = holding
+			tickers = append(tickers, ticker)
+		} else {
+			delete(profile.Holdings, ticker)
+		}
+	}
+	if _, err := profile.AddTickers(tickers); err != nil {
+		return err
+	}
+	return profile.Save()
+}
+
 // Reorder gets called by the column editor to either reverse sorting order
-// for the current column, or to pick another sort column.
-func (profile *Profile) Reorder() error {
-	if profile.selectedColumn == profile.SortColumn {
+// for the current column, or to pick another sort column. The secondary sort
+// keys, if any, are kept.
+func (profile *Profile) Reorder(name string) error {
+	if len(profile.SortKeys) > 0 && profile.SortKeys[0].Column != name {
+		profile.SortColumn = profile.selectedColumn // Pick new sort column.
+	} else if profile.selectedColumn == profile.SortColumn {
 		profile.Ascending = !profile.Ascending // Reverse sort order.
 	} else {
 		profile.SortColumn = profile.selectedColumn // Pick new sort column.
 	}
+	if len(profile.SortKeys) > 0 {
+		keys := []SortKey{{Column: name, Descending: !profile.Ascending}}
+		for _, key := range profile.SortKeys {
+			if key.Column != name {
+				keys = append(keys, key)
+			}
+		}
+		profile.SortKeys = keys
+	}
 	return profile.Save()
 }
 
-// Regroup flips the flag that controls whether the stock quotes are grouped
-// by advancing/declining issues.
-func (profile *Profile) Regroup() error {
-	profile.Grouped = !profile.Grouped
+// ThenSortBy gets called by the column editor to cycle the column through
+// the secondary sort keys following the given ones: it gets added in
+// ascending order, then reversed, and then removed.
+func (profile *Profile) ThenSortBy(keys []SortKey, name string) error {
+	if len(keys) == 0 || keys[0].Column == name {
+		return nil // Already the sort column.
+	}
+	profile.SortKeys = append([]SortKey(nil), keys...)
+	for i, key := range profile.SortKeys {
+		if key.Column == name {
+			if key.Descending {
+				profile.SortKeys = append(profile.SortKeys[:i], profile.SortKeys[i+1:]...)
+			} else {
+				profile.SortKeys[i].Descending = true
+			}
+			return profile.Save()
+		}
+	}
+	profile.SortKeys = append(profile.SortKeys, SortKey{Column: name})
 	return profile.Save()
 }
 
-// SetFilter creates a govaluate.EvaluableExpression.
+// SetFilter creates a govaluate.EvaluableExpression. The filter can refer
+// to the saved filters by name, ex. @momentum && last < 50.
 func (profile *Profile) SetFilter(filter string) error {
 	if len(filter) > 0 {
-		var err error
-		expr, err := govaluate.NewEvaluableExpression(filter)
+		if err := checkNames(filter); err != nil { // Report the positions within the filter as typed.
+			profile.filterExpression = nil
+			return err
+		}
+		expanded, err := profile.expandFilter(filter, nil)
+		if err != nil {
+			profile.filterExpression = nil
+			return err
+		}
+		expr, err := compileExpression(expanded)
 		if err != nil {
 			profile.filterExpression = nil
 			return err
@@ -246,32 +419,10 @@ func (profile *Profile) SetFilter(filter string) error {
 }
 
 // validateFilter performs a dry run evaluation to catch runtime logic errors.
+// The unknown functions and variables get reported by compileExpression
+// since the expression can't be parsed with those.
 func validateFilter(expr *govaluate.EvaluableExpression) error {
-	dummy := map[string]interface{}{
-		"ticker":        "",
-		"market":        "",
-		"currency":      "",
-		"last":          0.0,
-		"change":        0.0,
-		"changePercent": 0.0,
-		"open":          0.0,
-		"low":           0.0,
-		"high":          0.0,
-		"low52":         0.0,
-		"high52":        0.0,
-		"volume":        0.0,
-		"avgVolume":     0.0,
-		"pe":            0.0,
-		"peX":           0.0,
-		"dividend":      0.0,
-		"yield":         0.0,
-		"mktCap":        0.0,
-		"mktCapX":       0.0,
-		"advancing":     0.0,
-		"direction":     0,
-	}
-
-	_, err := expr.Evaluate(dummy)
+	_, err := expr.Evaluate(filterValues(&Stock{}))
 	return err
 }
 
@@ -279,3 +430,17 @@ func (profile *Profile) ToggleTimestamp() error {
 	profile.ShowTimestamp = !profile.ShowTimestamp
 	return profile.Save()
 }
+
+// ToggleExtendedHours switches to the next way to show pre-market and after
+// hours trading: off, extra row, or instead of the regular price and change.
+func (profile *Profile) ToggleExtendedHours() error {
+	switch profile.ExtendedHours {
+	case ExtendedRow:
+		profile.ExtendedHours = ExtendedReplace
+	case ExtendedReplace:
+		profile.ExtendedHours = ExtendedOff
+	default:
+		profile.ExtendedHours = ExtendedRow
+	}
+	return profile.Save()
+}
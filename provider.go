@@ -4,24 +4,36 @@
 
 package mop
 
+import "strings"
+
+// MarketData holds the quotes of the market summary symbols, ex. indices,
+// currencies, and commodities, as configured in the profile.
 type MarketData struct {
-	IsClosed  bool
-	Dow       map[string]string
-	Nasdaq    map[string]string
-	Sp500     map[string]string
-	Tokyo     map[string]string
-	HongKong  map[string]string
-	London    map[string]string
-	Frankfurt map[string]string
-	Yield     map[string]string
-	Oil       map[string]string
-	Yen       map[string]string
-	Euro      map[string]string
-	Gold      map[string]string
+	IsClosed bool          // True when U.S. markets are closed.
+	State    string        // Session of U.S. markets: pre, regular, post, or closed.
+	Quotes   []MarketQuote // Quotes of the market summary symbols.
+}
+
+// MarketQuote is the quote of a single market summary symbol.
+type MarketQuote struct {
+	Symbol    string    // Yahoo symbol, ex. ^GSPC.
+	Price     NullFloat // Latest price.
+	Change    NullFloat // Change since previous close.
+	ChangePct NullFloat // Change percent since previous close.
 }
 
 // StockProvider defines the interface for fetching market and quotes data.
 type StockProvider interface {
-	FetchMarket() (*MarketData, error)
+	FetchMarket(symbols []string) (*MarketData, error)
 	FetchQuotes(tickers []string) ([]Stock, error)
 }
+
+// Quote returns the quote of the given symbol, or nil if it's not there.
+func (data *MarketData) Quote(symbol string) *MarketQuote {
+	for i := range data.Quotes {
+		if strings.EqualFold(data.Quotes[i].Symbol, symbol) {
+			return &data.Quotes[i]
+		}
+	}
+	return nil
+}
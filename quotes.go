@@ -4,35 +4,61 @@
 
 package mop
 
+import (
+	"reflect"
+	"time"
+)
+
 const noDataIndicator = `N/A`
 
 // Stock stores quote information for the particular stock ticker. The data
 // for all the fields except 'Direction' is fetched using Yahoo market API.
+// Numeric values that Yahoo doesn't report are marked as not available;
+// they get formatted for display by the layout.
 type Stock struct {
-	Ticker          string `json:"symbol"`                      // Stock ticker.
-	LastTrade       string `json:"regularMarketPrice"`          // l1: last trade.
-	Change          string `json:"regularMarketChange"`         // c6: change real time.
-	ChangePct       string `json:"regularMarketChangePercent"`  // k2: percent change real time.
-	Open            string `json:"regularMarketOpen"`           // o: market open price.
-	Low             string `json:"regularMarketDayLow"`         // g: day's low.
-	High            string `json:"regularMarketDayHigh"`        // h: day's high.
-	Low52           string `json:"fiftyTwoWeekLow"`             // j: 52-weeks low.
-	High52          string `json:"fiftyTwoWeekHigh"`            // k: 52-weeks high.
-	Volume          string `json:"regularMarketVolume"`         // v: volume.
-	AvgVolume       string `json:"averageDailyVolume10Day"`     // a2: average volume.
-	PeRatio         string `json:"trailingPE"`                  // r2: P/E ration real time.
-	PeRatioX        string `json:"trailingPE"`                  // r: P/E ration (fallback when real time is N/A).
-	Dividend        string `json:"trailingAnnualDividendRate"`  // d: dividend.
-	Yield           string `json:"trailingAnnualDividendYield"` // y: dividend yield.
-	MarketCap       string `json:"marketCap"`                   // j3: market cap real time.
-	MarketCapX      string `json:"marketCap"`                   // j1: market cap (fallback when real time is N/A).
-	Currency        string `json:"currency"`                    // String code for currency of stock.
-	Direction       int    // -1 when change is < $0, 0 when change is = $0, 1 when change is > $0.
-	PreOpen         string `json:"preMarketChangePercent,omitempty"`
-	AfterHours      string `json:"postMarketChangePercent,omitempty"`
-	PreOpenColor    string
-	AfterHoursColor string
-	RowColor        string
+	Ticker      string                 `json:"symbol"`                      // Stock ticker.
+	LastTrade   NullFloat              `json:"regularMarketPrice"`          // l1: last trade.
+	Change      NullFloat              `json:"regularMarketChange"`         // c6: change real time.
+	ChangePct   NullFloat              `json:"regularMarketChangePercent"`  // k2: percent change real time.
+	Open        NullFloat              `json:"regularMarketOpen"`           // o: market open price.
+	Low         NullFloat              `json:"regularMarketDayLow"`         // g: day's low.
+	High        NullFloat              `json:"regularMarketDayHigh"`        // h: day's high.
+	Low52       NullFloat              `json:"fiftyTwoWeekLow"`             // j: 52-weeks low.
+	High52      NullFloat              `json:"fiftyTwoWeekHigh"`            // k: 52-weeks high.
+	Volume      NullInt                `json:"regularMarketVolume"`         // v: volume.
+	AvgVolume   NullInt                `json:"averageDailyVolume10Day"`     // a2: average volume.
+	PeRatio     NullFloat              `json:"trailingPE"`                  // r2: P/E ration real time.
+	PeRatioX    NullFloat              `json:"trailingPEX"`                 // r: P/E ration (fallback when real time is N/A).
+	Dividend    NullFloat              `json:"trailingAnnualDividendRate"`  // d: dividend.
+	Yield       NullFloat              `json:"trailingAnnualDividendYield"` // y: dividend yield (percent).
+	MarketCap   NullInt                `json:"marketCap"`                   // j3: market cap real time.
+	MarketCapX  NullInt                `json:"marketCapX"`                  // j1: market cap (fallback when real time is N/A).
+	Currency    string                 `json:"currency"`                    // String code for currency of stock.
+	Direction   int                    `json:"direction"`                   // -1 when change is < $0, 0 when change is = $0, 1 when change is > $0.
+	PreOpen     NullFloat              `json:"preMarketChangePercent"`      // Pre-market change percent.
+	AfterHours  NullFloat              `json:"postMarketChangePercent"`     // After hours change percent.
+	Time        time.Time              `json:"regularMarketTime"`           // Time of the last trade.
+	PrePrice    NullFloat              `json:"preMarketPrice"`              // Pre-market price.
+	PreChange   NullFloat              `json:"preMarketChange"`             // Pre-market change.
+	PreTime     time.Time              `json:"preMarketTime"`               // Time of the last pre-market trade.
+	PostPrice   NullFloat              `json:"postMarketPrice"`             // After hours price.
+	PostChange  NullFloat              `json:"postMarketChange"`            // After hours change.
+	PostTime    time.Time              `json:"postMarketTime"`              // Time of the last after hours trade.
+	MarketState string                 `json:"marketState"`                 // Trading session when the quote was fetched.
+	Session     string                 `json:"session"`                     // Current trading session of the stock exchange.
+	Sparkline   []float64              `json:"sparkline,omitempty"`         // Intraday prices, oldest first.
+	Fields      map[string]interface{} `json:"fields,omitempty"`            // All the fields returned by the data provider.
+	Computed    map[string]NullFloat   `json:"computed,omitempty"`          // Values of the computed columns keyed by column name.
+	Alias       string                 `json:"alias,omitempty"`             // Name shown instead of the ticker.
+	Tags        string                 `json:"tags,omitempty"`              // Comma separated tags of the stock.
+	Note        string                 `json:"note,omitempty"`              // User's note about the stock.
+
+	Shares      NullFloat `json:"shares"`      // Number of shares held.
+	Value       NullFloat `json:"value"`       // Current value of the position.
+	Cost        NullFloat `json:"cost"`        // Cost basis of the position.
+	DayPnl      NullFloat `json:"dayPnl"`      // Today's profit or loss of the position.
+	TotalPnl    NullFloat `json:"totalPnl"`    // Total profit or loss of the position.
+	TotalPnlPct NullFloat `json:"totalPnlPct"` // Total profit or loss as percent of the cost basis.
 }
 
 // Quotes stores relevant pointers as well as the array of stock quotes for
@@ -43,15 +69,25 @@ type Quotes struct {
 	stocks   []Stock       // Array of stock quote data.
 	errors   string        // Error string if any.
 	provider StockProvider // Provider for quotes.
+	alerts   *Alerts       // Alert rules checked whenever the quotes get fetched.
+	charts   *Charts       // Price history cache, nil if the provider has none.
 }
 
 // Sets the initial values and returns new Quotes struct.
 func NewQuotes(market *Market, profile *Profile, provider StockProvider) *Quotes {
+	// The charts for the Sparkline column get fetched along with the stock
+	// quotes so there is no point to refresh them more often.
+	refresh := profile.ChartRefresh
+	if refresh < profile.QuotesRefresh {
+		refresh = profile.QuotesRefresh
+	}
 	return &Quotes{
 		market:   market,
 		profile:  profile,
 		errors:   ``,
 		provider: provider,
+		alerts:   NewAlerts(profile),
+		charts:   NewCharts(provider, time.Duration(refresh)*time.Second),
 	}
 }
 
@@ -60,18 +96,32 @@ func NewQuotes(market *Market, profile *Profile, provider StockProvider) *Quotes
 func (quotes *Quotes) Fetch() (self *Quotes) {
 	self = quotes
 	if quotes.isReady() {
-		stocks, err := quotes.provider.FetchQuotes(quotes.profile.Tickers)
-		if err != nil {
-			quotes.errors = err.Error()
-		} else {
-			quotes.errors = ""
-			quotes.stocks = stocks
+		watchlist := quotes.profile.Watchlist
+		stocks, err := quotes.fetch(watchlist.Tickers, !quotes.profile.Hidden(`Sparkline`))
+		if watchlist != quotes.profile.Watchlist {
+			return quotes // User has switched to another watchlist meanwhile.
+		}
+		quotes.update(stocks, err)
+		if err == nil {
+			quotes.alerts.Evaluate(stocks)
 		}
 	}
 
 	return quotes
 }
 
+// Charts returns price history cache, or nil if the provider doesn't support
+// the charts.
+func (quotes *Quotes) Charts() *Charts {
+	return quotes.charts
+}
+
+// Alerts returns alert rules evaluator so that more notifiers could be
+// registered.
+func (quotes *Quotes) Alerts() *Alerts {
+	return quotes.alerts
+}
+
 // Ok returns two values: 1) boolean indicating whether the error has occurred,
 // and 2) the error text itself.
 func (quotes *Quotes) Ok() (bool, string) {
@@ -98,9 +148,201 @@ func (quotes *Quotes) RemoveTickers(tickers []string) (removed int, err error) {
 	return
 }
 
-// isReady returns true if we haven't fetched the quotes yet *or* the stock
-// market is still open and we might want to grab the latest quotes. In both
-// cases we make sure the list of requested tickers is not empty.
+// SelectWatchlist activates the watchlist with the given index and forces
+// the stock quotes to be fetched for its tickers.
+func (quotes *Quotes) SelectWatchlist(i int) error {
+	return quotes.switched(quotes.profile.SelectWatchlist(i))
+}
+
+// NextWatchlist activates the next (or previous for negative step)
+// watchlist and forces the stock quotes to be fetched for its tickers.
+func (quotes *Quotes) NextWatchlist(step int) error {
+	return quotes.switched(quotes.profile.NextWatchlist(step))
+}
+
+// AddWatchlist creates new watchlist and activates it. The function gets
+// called from the line editor when user enters new watchlist name.
+func (quotes *Quotes) AddWatchlist(name string) error {
+	return quotes.switched(quotes.profile.AddWatchlist(name))
+}
+
+// RemoveWatchlist deletes active watchlist and activates the previous one.
+func (quotes *Quotes) RemoveWatchlist() error {
+	return quotes.switched(quotes.profile.RemoveWatchlist())
+}
+
+// switched discards stock quotes of the previously active watchlist unless
+// the switch has failed.
+func (quotes *Quotes) switched(err error) error {
+	if err == nil {
+		quotes.stocks = nil // Force fetch.
+	}
+	return err
+}
+
+// SetHolding saves the position in the given stock and recalculates the
+// values of all positions. The ticker gets added to the list if necessary.
+// The function gets called from the line editor when user enters the
+// position.
+func (quotes *Quotes) SetHolding(ticker string, holding *Holding) error {
+	if err := quotes.profile.SetHolding(ticker, holding); err != nil {
+		return err
+	}
+	if holding.Shares > 0 {
+		if _, err := quotes.AddTickers([]string{ticker}); err != nil {
+			return err
+		}
+	}
+	quotes.valuate()
+
+	return nil
+}
+
+// valuate calculates the value and profit and loss of the stocks held.
+func (quotes *Quotes) valuate() {
+	for i := range quotes.stocks {
+		quotes.stocks[i].valuate(quotes.profile.Holdings[quotes.stocks[i].Ticker])
+	}
+}
+
+// fetch requests the stock quotes from the provider along with the charts
+// for the Sparkline column, if needed. It doesn't change the quotes so that
+// the caller could do it without holding any locks; see update.
+func (quotes *Quotes) fetch(tickers []string, sparklines bool) ([]Stock, error) {
+	stocks, err := quotes.provider.FetchQuotes(tickers)
+	if err == nil && sparklines && quotes.charts != nil {
+		quotes.charts.FetchAll(tickers, Range1D)
+	}
+	return stocks, err
+}
+
+// update replaces the stock quotes with the ones returned by fetch, or sets
+// the error.
+func (quotes *Quotes) update(stocks []Stock, err error) {
+	if err != nil {
+		quotes.errors = err.Error()
+		return
+	}
+	quotes.errors = ``
+	quotes.stocks = stocks
+	quotes.valuate()
+	updateSessions(quotes.stocks, time.Now())
+	annotate(quotes.stocks, quotes.profile)
+	quotes.sparklines()
+}
+
+// sparklines sets intraday price history of the stocks from the charts
+// fetched along with the quotes. The charts that couldn't be fetched are left
+// blank, and none are set while the Sparkline column is hidden.
+func (quotes *Quotes) sparklines() {
+	if quotes.charts == nil || quotes.profile.Hidden(`Sparkline`) {
+		return
+	}
+	for i := range quotes.stocks {
+		quotes.stocks[i].Sparkline = quotes.charts.Cached(quotes.stocks[i].Ticker, Range1D).Sparkline(sparklineWidth)
+	}
+}
+
+// value returns the value of the given Stock field, computed column, or
+// Yahoo quote field. It returns nil if the quote field is not available.
+func (stock *Stock) value(name string) interface{} {
+	if field := reflect.ValueOf(stock).Elem().FieldByName(name); field.IsValid() {
+		return field.Interface()
+	}
+	if value, ok := stock.Computed[name]; ok {
+		return value
+	}
+	return stock.Fields[name]
+}
+
+// trend returns the change percent of the intraday price history.
+func (stock *Stock) trend() NullFloat {
+	if len(stock.Sparkline) < 2 || stock.Sparkline[0] == 0 {
+		return NullFloat{}
+	}
+	first, last := stock.Sparkline[0], stock.Sparkline[len(stock.Sparkline)-1]
+	return Float((last - first) / first * 100)
+}
+
+// session returns current trading session of the stock exchange as per its
+// calendar, or the session reported along with the quote if the exchange is
+// not known.
+func (stock *Stock) session(now time.Time) string {
+	if exchange := ExchangeFor(stock.Ticker); exchange != nil {
+		return exchange.Session(now)
+	}
+	return stock.MarketState
+}
+
+// extended returns the price, change, change percent, and time of the last
+// pre-market or after hours trade while the exchange is in the respective
+// session, or false if there is none.
+func (stock *Stock) extended() (price, change, changePct NullFloat, at time.Time, ok bool) {
+	switch {
+	case stock.Session == SessionPre && stock.PrePrice.Valid:
+		return stock.PrePrice, stock.PreChange, stock.PreOpen, stock.PreTime, true
+	case stock.Session == SessionPost && stock.PostPrice.Valid:
+		return stock.PostPrice, stock.PostChange, stock.AfterHours, stock.PostTime, true
+	}
+	return
+}
+
+// updateSessions sets current trading session of all the given stocks.
+func updateSessions(stocks []Stock, now time.Time) {
+	for i := range stocks {
+		stocks[i].Session = stocks[i].session(now)
+	}
+}
+
+// Refresh returns how long to wait before fetching the stock quotes again.
+// While any of the stocks is being traded, pre-market and after hours
+// included, it's the given refresh interval. When all the exchanges are
+// closed it's closedRefresh or till the next trading session, whichever
+// comes first. Negative closedRefresh waits for the next session.
+func (quotes *Quotes) Refresh(refresh, closedRefresh time.Duration) time.Duration {
+	return quotes.refresh(refresh, closedRefresh, time.Now())
+}
+
+// refresh implements Refresh as of the given time. The stocks traded on the
+// exchanges with unknown hours might start trading any moment, so when they
+// are closed the wait is never longer than closedRefresh (one hour if it's
+// negative).
+func (quotes *Quotes) refresh(refresh, closedRefresh time.Duration, now time.Time) time.Duration {
+	if len(quotes.stocks) == 0 {
+		return refresh
+	}
+
+	var next time.Time
+	unknown := false
+	for _, stock := range quotes.stocks {
+		if stock.MarketState != SessionClosed {
+			return refresh
+		}
+		exchange := ExchangeFor(stock.Ticker)
+		if exchange == nil {
+			unknown = true
+			continue
+		}
+		if exchange.Session(now) != SessionClosed {
+			return refresh // The session has started since the quote was fetched.
+		}
+		if start := exchange.NextSession(now); next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+
+	wait := closedWait(closedRefresh, now, next)
+	if unknown {
+		if unknownWait := closedWait(closedRefresh, now, time.Time{}); unknownWait < wait {
+			wait = unknownWait
+		}
+	}
+	return wait
+}
+
+// isReady returns true if the list of requested tickers is not empty. How
+// often the quotes get fetched outside of the trading hours is decided by
+// Refresh.
 func (quotes *Quotes) isReady() bool {
-	return (quotes.stocks == nil || !quotes.market.IsClosed) && len(quotes.profile.Tickers) > 0
+	return len(quotes.profile.Tickers) > 0
 }
@@ -0,0 +1,48 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotesRefresh(t *testing.T) {
+	saturday := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC) // NYSE opens on Monday at 8:00 UTC, Tokyo at 0:00 UTC.
+	monday := time.Date(2026, time.October, 19, 14, 0, 0, 0, time.UTC)   // NYSE regular session.
+	closed := func(tickers ...string) []Stock {
+		var stocks []Stock
+		for _, ticker := range tickers {
+			stocks = append(stocks, Stock{Ticker: ticker, MarketState: SessionClosed})
+		}
+		return stocks
+	}
+
+	tests := []struct {
+		name   string
+		stocks []Stock
+		now    time.Time
+		closed time.Duration // ClosedRefresh.
+		want   time.Duration
+	}{
+		{`no stocks`, nil, saturday, time.Hour, time.Minute},
+		{`trading`, []Stock{{Ticker: `AAPL`, MarketState: SessionPost}}, saturday, time.Hour, time.Minute},
+		{`closed`, closed(`AAPL`), saturday, time.Hour, time.Hour},
+		{`till the next session`, closed(`AAPL`), saturday, -1, 44 * time.Hour},
+		{`closed till the session closer than closedRefresh`, closed(`AAPL`, `7203.T`), saturday, 100 * time.Hour, 36 * time.Hour},
+		{`session has started`, closed(`AAPL`), monday, time.Hour, time.Minute},
+		{`unknown exchange`, closed(`005930.KS`), saturday, 30 * time.Minute, 30 * time.Minute},
+		{`unknown exchange without closedRefresh`, closed(`005930.KS`, `AAPL`), saturday, -1, time.Hour},
+		{`unknown exchange and the session closer than closedRefresh`, closed(`005930.KS`, `7203.T`), saturday, 100 * time.Hour, 36 * time.Hour},
+		{`unknown exchange before the stock being traded`, append(closed(`005930.KS`), Stock{Ticker: `AAPL`, MarketState: SessionRegular}), saturday, time.Hour, time.Minute},
+		{`unknown exchange before the session that has started`, closed(`005930.KS`, `AAPL`), monday, time.Hour, time.Minute},
+	}
+	for _, test := range tests {
+		quotes := &Quotes{stocks: test.stocks}
+		if got := quotes.refresh(time.Minute, test.closed, test.now); got != test.want {
+			t.Errorf(`%s: got %v, want %v`, test.name, got, test.want)
+		}
+	}
+}
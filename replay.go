@@ -0,0 +1,164 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshot holds quote results decoded from a single recorded Yahoo
+// response, or the error that response stands for.
+type snapshot struct {
+	name    string                   // File name the snapshot was loaded from.
+	results []map[string]interface{} // Decoded quote results.
+	err     error                    // Error reported by the recorded response.
+}
+
+// ReplayProvider implements StockProvider by serving recorded Yahoo
+// `quoteResponse` JSON files instead of hitting the network. When given
+// several snapshots it steps through them either on every quotes fetch or,
+// if the interval is set, as time goes by. The last snapshot stays in effect
+// once the sequence is exhausted.
+type ReplayProvider struct {
+	sync.Mutex
+	snapshots []snapshot    // Recorded responses in playback order.
+	interval  time.Duration // Time between snapshots; zero to step on every FetchQuotes.
+	started   time.Time     // When the playback has started.
+	current   int           // Index of the snapshot served by the last fetch.
+	fetches   int           // Number of FetchQuotes calls so far.
+}
+
+// NewReplayProvider loads recorded responses from the given paths. Each path
+// can be a JSON file, a directory (all its *.json files are loaded in name
+// order), or a glob pattern.
+func NewReplayProvider(paths []string, interval time.Duration) (*ReplayProvider, error) {
+	files, err := replayFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no replay files found in %s", strings.Join(paths, `, `))
+	}
+
+	replay := &ReplayProvider{interval: interval, started: time.Now()}
+	for _, file := range files {
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		results, err := decodeResults(body)
+		if _, ok := err.(*yahooError); !ok && err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		replay.snapshots = append(replay.snapshots, snapshot{name: file, results: results, err: err})
+	}
+
+	return replay, nil
+}
+
+// FetchMarket picks the quotes of market summary symbols out of the current
+// snapshot. Symbols missing from the snapshot are shown as N/A.
+func (replay *ReplayProvider) FetchMarket(symbols []string) (*MarketData, error) {
+	replay.Lock()
+	defer replay.Unlock()
+
+	snap := replay.snapshots[replay.current]
+	if snap.err != nil {
+		return nil, snap.err
+	}
+
+	results := []map[string]interface{}{}
+	for _, symbol := range symbols {
+		if result := lookupResult(snap.results, symbol); result != nil {
+			results = append(results, result)
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no market data in %s", filepath.Base(snap.name))
+	}
+
+	return buildMarket(results), nil
+}
+
+// FetchQuotes advances to the next snapshot if it's time to, and returns
+// the quotes it has for the requested tickers. Tickers that are missing
+// from the snapshot are skipped just like Yahoo does for unknown symbols.
+func (replay *ReplayProvider) FetchQuotes(tickers []string) ([]Stock, error) {
+	replay.Lock()
+	defer replay.Unlock()
+
+	replay.advance()
+	snap := replay.snapshots[replay.current]
+	if snap.err != nil {
+		return nil, snap.err
+	}
+
+	results := []map[string]interface{}{}
+	for _, ticker := range tickers {
+		if result := lookupResult(snap.results, ticker); result != nil {
+			results = append(results, result)
+		}
+	}
+
+	return buildStocks(results), nil
+}
+
+// advance picks the snapshot to serve next.
+func (replay *ReplayProvider) advance() {
+	next := replay.fetches
+	if replay.interval > 0 {
+		next = int(time.Since(replay.started) / replay.interval)
+	}
+	if next >= len(replay.snapshots) {
+		next = len(replay.snapshots) - 1
+	}
+	replay.current = next
+	replay.fetches++
+}
+
+// lookupResult finds the quote result for the given symbol, or returns nil.
+func lookupResult(results []map[string]interface{}, symbol string) map[string]interface{} {
+	for _, result := range results {
+		if s, ok := result[`symbol`].(string); ok && strings.EqualFold(s, symbol) {
+			return result
+		}
+	}
+	return nil
+}
+
+// replayFiles expands the list of files, directories, and glob patterns into
+// the list of file names.
+func replayFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(path, `*.json`))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(matches)
+			files = append(files, matches...)
+		} else if err == nil {
+			files = append(files, path)
+		} else {
+			matches, globErr := filepath.Glob(path)
+			if globErr != nil || len(matches) == 0 {
+				return nil, err
+			}
+			sort.Strings(matches)
+			files = append(files, matches...)
+		}
+	}
+
+	return files, nil
+}
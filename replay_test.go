@@ -0,0 +1,160 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubProvider is the stock provider that returns canned results.
+type stubProvider struct {
+	market *MarketData
+	stocks []Stock
+	err    error
+}
+
+func (stub *stubProvider) FetchMarket(symbols []string) (*MarketData, error) {
+	return stub.market, stub.err
+}
+
+func (stub *stubProvider) FetchQuotes(tickers []string) ([]Stock, error) {
+	return stub.stocks, stub.err
+}
+
+func TestRecordAndPlayback(t *testing.T) {
+	stub := &stubProvider{
+		market: &MarketData{State: SessionRegular, Quotes: []MarketQuote{
+			{Symbol: `^GSPC`, Price: Float(5000.5), Change: Float(-12.25), ChangePct: Float(-0.24)},
+			{Symbol: `^IXIC`}, // Not available.
+		}},
+		stocks: []Stock{
+			{Ticker: `AAPL`, LastTrade: Float(190.5), Volume: Int(52000000), Time: time.Unix(1700000000, 0).UTC()},
+			{Ticker: `BRK-B`, LastTrade: Float(410), PeRatio: NullFloat{}, Fields: map[string]interface{}{`exchange`: `NYQ`}},
+		},
+	}
+	tests := []struct {
+		name   string
+		record func(recorder *RecordingProvider)
+		market *MarketData
+		stocks []Stock
+		err    string // Error expected from the quotes, if any.
+	}{
+		{
+			name: `quotes and market`,
+			record: func(recorder *RecordingProvider) {
+				recorder.FetchMarket([]string{`^GSPC`, `^IXIC`})
+				recorder.FetchQuotes([]string{`AAPL`, `BRK-B`})
+			},
+			market: stub.market,
+			stocks: stub.stocks,
+		},
+		{
+			name: `provider error`,
+			record: func(recorder *RecordingProvider) {
+				recorder.FetchMarket([]string{`^GSPC`})
+				stub.err = &yahooError{`Unauthorized`, `Invalid Crumb`}
+				recorder.FetchQuotes([]string{`AAPL`})
+				stub.err = nil
+			},
+			market: stub.market,
+			err:    `Unauthorized: Invalid Crumb`,
+		},
+		{
+			name: `missing recording`,
+			record: func(recorder *RecordingProvider) {
+				recorder.FetchMarket([]string{`^GSPC`})
+			},
+			market: stub.market,
+			err:    `no stock quotes recorded in the session`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), `session.jsonl`)
+			recorder, err := NewRecordingProvider(stub, filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.record(recorder)
+			if err := recorder.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			playback, err := NewPlaybackProvider(filename, 1.0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			market, err := playback.FetchMarket(nil)
+			if err != nil || !reflect.DeepEqual(market, test.market) {
+				t.Errorf("got market %+v (%v)\nwant %+v", market, err, test.market)
+			}
+			stocks, err := playback.FetchQuotes(nil)
+			if test.err != `` {
+				if err == nil || err.Error() != test.err {
+					t.Errorf(`got error %v, want %s`, err, test.err)
+				}
+			} else if err != nil || !reflect.DeepEqual(stocks, test.stocks) {
+				t.Errorf("got stocks %+v (%v)\nwant %+v", stocks, err, test.stocks)
+			}
+		})
+	}
+
+	if _, err := NewPlaybackProvider(filepath.Join(t.TempDir(), `missing.jsonl`), 1.0, 0); err == nil {
+		t.Errorf(`playing back missing session: got no error`)
+	}
+}
+
+func TestReplayProvider(t *testing.T) {
+	dir := t.TempDir()
+	recordings := []string{
+		`{"quoteResponse":{"result":[{"symbol":"AAPL","regularMarketPrice":190.5,"regularMarketChange":1.5},{"symbol":"^GSPC","regularMarketPrice":5000}]}}`,
+		`{"quoteResponse":{"result":[{"symbol":"AAPL","regularMarketPrice":191,"regularMarketChange":-0.5}]}}`,
+		`{"finance":{"result":null,"error":{"code":"Unauthorized","description":"Invalid Crumb"}}}`,
+	}
+	for i, recording := range recordings {
+		filename := filepath.Join(dir, string(rune('a'+i))+`.json`)
+		if err := ioutil.WriteFile(filename, []byte(recording), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	replay, err := NewReplayProvider([]string{dir}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market, err := replay.FetchMarket([]string{`^GSPC`, `^DJI`})
+	if err != nil || len(market.Quotes) != 1 || market.Quotes[0].Price != Float(5000) {
+		t.Errorf(`got market %+v (%v)`, market, err)
+	}
+	tests := []struct {
+		last float64 // Zero when the quotes fail.
+		err  error
+	}{
+		{190.5, nil},
+		{191, nil},
+		{0, &yahooError{`Unauthorized`, `Invalid Crumb`}},
+		{0, &yahooError{`Unauthorized`, `Invalid Crumb`}}, // The last recording stays in effect.
+	}
+	for i, test := range tests {
+		stocks, err := replay.FetchQuotes([]string{`AAPL`, `MSFT`})
+		if test.err != nil {
+			var e *yahooError
+			if !errors.As(err, &e) || *e != *test.err.(*yahooError) {
+				t.Errorf(`#%d: got %v, want %v`, i+1, err, test.err)
+			}
+		} else if err != nil || len(stocks) != 1 || stocks[0].LastTrade != Float(test.last) {
+			t.Errorf(`#%d: got %+v (%v), want AAPL at %v`, i+1, stocks, err, test.last)
+		}
+	}
+
+	if _, err := NewReplayProvider([]string{filepath.Join(dir, `*.txt`)}, 0); err == nil {
+		t.Errorf(`replaying missing recordings: got no error`)
+	}
+}
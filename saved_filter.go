@@ -0,0 +1,260 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Number of filters remembered in the filter history of the session.
+const filterHistorySize = 50
+
+// Saved filter reference within the filter expression, ex. @momentum.
+var savedFilterReference = regexp.MustCompile(`^@\w+`)
+
+// SavedFilter is the filter expression saved under a name so that it could
+// be applied with the s key, or referred to as @name in other filters.
+type SavedFilter struct {
+	Name       string // Filter name, ex. momentum.
+	Expression string // Filter expression, ex. changePercent < -3 && volume > avgVolume * 2.
+}
+
+// SavedFilter returns the saved filter with the given name, or nil if there
+// is none. The names are case insensitive.
+func (profile *Profile) SavedFilter(name string) *SavedFilter {
+	for _, saved := range profile.SavedFilters {
+		if strings.EqualFold(saved.Name, name) {
+			return saved
+		}
+	}
+	return nil
+}
+
+// SaveFilter saves the filter expression under the given name replacing the
+// saved filter with the same name, if any. Blank expression saves the
+// current filter.
+func (profile *Profile) SaveFilter(name, expression string) error {
+	name = strings.TrimPrefix(strings.TrimSpace(name), `@`)
+	if err := checkFilterName(name); err != nil {
+		return err
+	}
+	if expression = strings.TrimSpace(expression); expression == `` {
+		expression = profile.Filter
+	}
+	if expression == `` {
+		return fmt.Errorf("there is no filter to save")
+	}
+	if err := profile.checkFilter(expression, name); err != nil {
+		return err
+	}
+
+	if saved := profile.SavedFilter(name); saved != nil {
+		saved.Expression = expression
+	} else {
+		profile.SavedFilters = append(profile.SavedFilters, &SavedFilter{Name: name, Expression: expression})
+	}
+
+	// The watchlist filters might refer to the saved filter that has changed.
+	active := profile.Watchlist
+	for _, watchlist := range profile.Watchlists {
+		profile.Watchlist = watchlist
+		profile.SetFilter(watchlist.Filter)
+	}
+	profile.Watchlist = active
+
+	return profile.Save()
+}
+
+// RemoveFilter deletes the saved filter with the given name unless one of
+// the watchlist filters or other saved filters refers to it.
+func (profile *Profile) RemoveFilter(name string) error {
+	name = strings.TrimPrefix(strings.TrimSpace(name), `@`)
+	for i, saved := range profile.SavedFilters {
+		if !strings.EqualFold(saved.Name, name) {
+			continue
+		}
+		for _, watchlist := range profile.Watchlists {
+			if profile.refersTo(watchlist.Filter, saved.Name) {
+				return fmt.Errorf("filter `%s` is used by watchlist `%s`", saved.Name, watchlist.Name)
+			}
+		}
+		for _, other := range profile.SavedFilters {
+			if profile.refersTo(other.Expression, saved.Name) {
+				return fmt.Errorf("filter `%s` is used by filter `%s`", saved.Name, other.Name)
+			}
+		}
+		profile.SavedFilters = append(profile.SavedFilters[:i], profile.SavedFilters[i+1:]...)
+		return profile.Save()
+	}
+	return fmt.Errorf("no saved filter `%s`", name)
+}
+
+// RenameFilter renames the saved filter and updates the references to it in
+// the watchlist filters, other saved filters, and the filter history.
+func (profile *Profile) RenameFilter(name, newName string) error {
+	name = strings.TrimPrefix(strings.TrimSpace(name), `@`)
+	newName = strings.TrimPrefix(strings.TrimSpace(newName), `@`)
+	saved := profile.SavedFilter(name)
+	if saved == nil {
+		return fmt.Errorf("no saved filter `%s`", name)
+	}
+	if err := checkFilterName(newName); err != nil {
+		return err
+	}
+	if other := profile.SavedFilter(newName); other != nil && other != saved {
+		return fmt.Errorf("filter `%s` exists already", other.Name)
+	}
+
+	rename := func(filter string) string {
+		renamed, err := replaceReferences(filter, func(reference string) (string, error) {
+			if strings.EqualFold(reference[1:], saved.Name) {
+				return `@` + newName, nil
+			}
+			return reference, nil
+		})
+		if err != nil {
+			return filter // Broken filter is left as is.
+		}
+		return renamed
+	}
+	for _, watchlist := range profile.Watchlists {
+		watchlist.Filter = rename(watchlist.Filter)
+	}
+	for _, other := range profile.SavedFilters {
+		other.Expression = rename(other.Expression)
+	}
+	for i, filter := range profile.filterHistory {
+		profile.filterHistory[i] = rename(filter)
+	}
+	saved.Name = newName
+
+	return profile.Save()
+}
+
+// Adds the filter to the filter history of the session moving it to the end
+// if it's there already.
+// -----------------------------------------------------------------------------
+func (profile *Profile) rememberFilter(filter string) {
+	if filter = strings.TrimSpace(filter); filter == `` {
+		return
+	}
+	for i, previous := range profile.filterHistory {
+		if previous == filter {
+			profile.filterHistory = append(profile.filterHistory[:i], profile.filterHistory[i+1:]...)
+			break
+		}
+	}
+	profile.filterHistory = append(profile.filterHistory, filter)
+	if len(profile.filterHistory) > filterHistorySize {
+		profile.filterHistory = profile.filterHistory[1:]
+	}
+}
+
+// Compiles and validates the filter without setting it. The names of the
+// saved filters the filter is going to be saved as are passed along to catch
+// the filters that refer to themselves.
+// -----------------------------------------------------------------------------
+func (profile *Profile) checkFilter(filter string, expanding ...string) error {
+	if err := checkNames(filter); err != nil {
+		return err
+	}
+	expanded, err := profile.expandFilter(filter, expanding)
+	if err != nil {
+		return err
+	}
+	expr, err := compileExpression(expanded)
+	if err != nil {
+		return err
+	}
+	return validateFilter(expr)
+}
+
+// Returns an error unless the name could be used in the saved filter
+// reference.
+// -----------------------------------------------------------------------------
+func checkFilterName(name string) error {
+	if reference := `@` + name; savedFilterReference.FindString(reference) != reference {
+		return fmt.Errorf("filter name `%s` can only have letters, digits, and underscores", name)
+	}
+	return nil
+}
+
+// Replaces the saved filter references, ex. @momentum, with the saved filter
+// expressions in parentheses.
+// -----------------------------------------------------------------------------
+func (profile *Profile) expandFilter(filter string, expanding []string) (string, error) {
+	return replaceReferences(filter, func(reference string) (string, error) {
+		saved := profile.SavedFilter(reference[1:])
+		if saved == nil {
+			return ``, fmt.Errorf("no saved filter `%s`", reference)
+		}
+		for _, name := range expanding {
+			if strings.EqualFold(name, saved.Name) {
+				return ``, fmt.Errorf("saved filter `%s` refers to itself", saved.Name)
+			}
+		}
+		expression, err := profile.expandFilter(saved.Expression, append(expanding, saved.Name))
+		if err != nil {
+			return ``, err
+		}
+		return `(` + expression + `)`, nil
+	})
+}
+
+// Returns true if the filter refers to the saved filter with the given name.
+// -----------------------------------------------------------------------------
+func (profile *Profile) refersTo(filter, name string) bool {
+	found := false
+	replaceReferences(filter, func(reference string) (string, error) {
+		found = found || strings.EqualFold(reference[1:], name)
+		return reference, nil
+	})
+	return found
+}
+
+// Calls the given function for each saved filter reference in the filter,
+// and replaces the reference with what the function returns. The references
+// within quoted strings, escaped quotes included, and within escaped
+// variable names, ex. [@name], are left as is just like checkNames does.
+// -----------------------------------------------------------------------------
+func replaceReferences(filter string, replace func(string) (string, error)) (string, error) {
+	var str strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(filter); i++ {
+		ch := filter[i]
+		switch {
+		case quote == '[':
+			if ch == ']' {
+				quote = 0
+			}
+		case quote != 0:
+			if ch == '\\' && i+1 < len(filter) {
+				str.WriteByte(ch)
+				i++
+				ch = filter[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '[':
+			quote = ch
+		case ch == '@':
+			reference := savedFilterReference.FindString(filter[i:])
+			if reference == `` {
+				return ``, fmt.Errorf("saved filter name is missing after @")
+			}
+			replacement, err := replace(reference)
+			if err != nil {
+				return ``, err
+			}
+			str.WriteString(replacement)
+			i += len(reference) - 1
+			continue
+		}
+		str.WriteByte(ch)
+	}
+	return str.String(), nil
+}
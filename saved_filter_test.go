@@ -0,0 +1,192 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// savedFilters returns new profile with the given saved filters, name
+// followed by expression.
+func savedFilters(t *testing.T, filters ...string) *Profile {
+	profile, err := NewProfile(filepath.Join(t.TempDir(), `moprc`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(filters); i += 2 {
+		profile.SavedFilters = append(profile.SavedFilters, &SavedFilter{Name: filters[i], Expression: filters[i+1]})
+	}
+	return profile
+}
+
+func TestExpandFilter(t *testing.T) {
+	profile := savedFilters(t,
+		`movers`, `changePercent < -3`,
+		`busy`, `volume > avgVolume * 2`,
+		`busyMovers`, `@movers && @busy`,
+		`self`, `@self || last > 1`,
+		`ping`, `@pong && last > 1`,
+		`pong`, `@Ping || last < 1`,
+		`broken`, `@missing`,
+	)
+	tests := []struct {
+		filter string
+		want   string // Expanded filter, or the error.
+	}{
+		{`last > 100`, `last > 100`},
+		{`@movers`, `(changePercent < -3)`},
+		{`@MOVERS && ticker != 'AAPL'`, `(changePercent < -3) && ticker != 'AAPL'`},
+		{`@busyMovers || @busy`, `((changePercent < -3) && (volume > avgVolume * 2)) || (volume > avgVolume * 2)`},
+		{`@movers_ && true`, "no saved filter `@movers_`"},
+		{`note == '@movers' && @busy`, `note == '@movers' && (volume > avgVolume * 2)`},
+		{`note == "@movers"`, `note == "@movers"`},
+		{`note == 'it\'s @movers' || @busy`, `note == 'it\'s @movers' || (volume > avgVolume * 2)`},
+		{`note == "say \"@movers\"" || @busy`, `note == "say \"@movers\"" || (volume > avgVolume * 2)`},
+		{`[@movers] > 1 || @busy`, `[@movers] > 1 || (volume > avgVolume * 2)`},
+		{`@ && true`, `saved filter name is missing after @`},
+		{`@self`, "saved filter `self` refers to itself"},
+		{`@ping`, "saved filter `ping` refers to itself"},
+		{`@broken`, "no saved filter `@missing`"},
+	}
+	for _, test := range tests {
+		got, err := profile.expandFilter(test.filter, nil)
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", test.filter, got, test.want)
+		}
+	}
+}
+
+func TestSaveFilterCycles(t *testing.T) {
+	profile := savedFilters(t)
+	tests := []struct {
+		name       string
+		expression string
+		err        string // Blank if the filter gets saved.
+	}{
+		{`a`, `last > 1`, ``},
+		{`b`, `@a && volume > 0`, ``},
+		{`c`, `@b || @a`, ``},
+		{`a`, `@a`, "saved filter `a` refers to itself"},
+		{`a`, `@b`, "saved filter `a` refers to itself"},
+		{`A`, `@c && true`, "saved filter `a` refers to itself"},
+		{`a`, `last > 2`, ``},
+		{`d`, `@e`, "no saved filter `@e`"},
+		{`bad name`, `last > 1`, "filter name `bad name` can only have letters, digits, and underscores"},
+	}
+	for _, test := range tests {
+		err := profile.SaveFilter(test.name, test.expression)
+		if test.err == `` && err != nil || test.err != `` && (err == nil || err.Error() != test.err) {
+			t.Errorf(`%s = %s: got %v, want %s`, test.name, test.expression, err, test.err)
+		}
+	}
+	if saved := profile.SavedFilter(`a`); saved == nil || saved.Expression != `last > 2` {
+		t.Errorf(`got saved filter %+v`, saved)
+	}
+}
+
+func TestRefersTo(t *testing.T) {
+	profile := savedFilters(t)
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`@movers`, true},
+		{`last > 1 && @Movers`, true},
+		{`@moversToo`, false},
+		{`note == '@movers'`, false},
+		{`note == 'it\'s @movers'`, false},
+		{`[@movers] > 1`, false},
+		{``, false},
+	}
+	for _, test := range tests {
+		if got := profile.refersTo(test.filter, `movers`); got != test.want {
+			t.Errorf(`%s: got %v, want %v`, test.filter, got, test.want)
+		}
+	}
+}
+
+func TestRenameFilter(t *testing.T) {
+	profile := savedFilters(t,
+		`movers`, `changePercent < -3`,
+		`busy`, `@movers && volume > avgVolume * 2 || @moversToo`,
+		`moversToo`, `changePercent > 3`,
+	)
+	profile.Filter = `@Movers && note != '@movers' && note != 'it\'s @movers'`
+	profile.rememberFilter(`@movers || @busy`)
+
+	if err := profile.RenameFilter(`@movers`, `momentum`); err != nil {
+		t.Fatal(err)
+	}
+	if want := `@momentum && note != '@movers' && note != 'it\'s @movers'`; profile.Filter != want {
+		t.Errorf("got filter  %s\nwant filter %s", profile.Filter, want)
+	}
+	if want := `@momentum && volume > avgVolume * 2 || @moversToo`; profile.SavedFilter(`busy`).Expression != want {
+		t.Errorf("got saved filter  %s\nwant saved filter %s", profile.SavedFilter(`busy`).Expression, want)
+	}
+	if want := `@momentum || @busy`; profile.filterHistory[0] != want {
+		t.Errorf("got filter history  %s\nwant filter history %s", profile.filterHistory[0], want)
+	}
+	if profile.SavedFilter(`movers`) != nil || profile.SavedFilter(`momentum`) == nil {
+		t.Errorf(`the filter has not been renamed: %+v`, profile.SavedFilters)
+	}
+
+	saved, err := NewProfile(profile.filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.SavedFilter(`momentum`) == nil || saved.Filter != profile.Filter {
+		t.Errorf(`the renamed filter has not been saved: %+v`, saved.SavedFilters)
+	}
+
+	errors := []struct {
+		name, newName string
+		err           string
+	}{
+		{`movers`, `fast`, "no saved filter `movers`"},
+		{`momentum`, `busy`, "filter `busy` exists already"},
+		{`momentum`, `no way`, "filter name `no way` can only have letters, digits, and underscores"},
+	}
+	for _, test := range errors {
+		if err := profile.RenameFilter(test.name, test.newName); err == nil || err.Error() != test.err {
+			t.Errorf(`%s>%s: got %v, want %s`, test.name, test.newName, err, test.err)
+		}
+	}
+	if err := profile.RenameFilter(`momentum`, `Momentum`); err != nil {
+		t.Errorf(`changing the case of the name: %v`, err)
+	}
+}
+
+func TestRemoveFilter(t *testing.T) {
+	profile := savedFilters(t,
+		`movers`, `changePercent < -3`,
+		`busy`, `@movers && volume > avgVolume * 2`,
+		`quiet`, `volume < avgVolume`,
+	)
+	profile.Filter = `@quiet`
+
+	tests := []struct {
+		name string
+		err  string // Blank if the filter gets removed.
+	}{
+		{`movers`, "filter `movers` is used by filter `busy`"},
+		{`quiet`, "filter `quiet` is used by watchlist `Default`"},
+		{`nope`, "no saved filter `nope`"},
+		{`@busy`, ``},
+		{`Movers`, ``},
+	}
+	for _, test := range tests {
+		err := profile.RemoveFilter(test.name)
+		if test.err == `` && err != nil || test.err != `` && (err == nil || err.Error() != test.err) {
+			t.Errorf(`%s: got %v, want %s`, test.name, err, test.err)
+		}
+	}
+	if len(profile.SavedFilters) != 1 || profile.SavedFilters[0].Name != `quiet` {
+		t.Errorf(`got saved filters %+v`, profile.SavedFilters)
+	}
+}
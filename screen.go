@@ -16,16 +16,22 @@ import (
 // Screen is thin wrapper around Termbox library to provide basic display
 // capabilities as required by Mop.
 type Screen struct {
-	width      int        // Current number of columns.
-	height     int        // Current number of rows.
-	cleared    bool       // True after the screens gets cleared.
-	layout     *Layout    // Pointer to layout (gets created by screen).
-	markup     *Markup    // Pointer to markup processor (gets created by screen).
-	pausedAt   *time.Time // Timestamp of the pause request or nil if none.
-	profile    *Profile   // Pointer to profile passed to NewScreen
-	offset     int        // Offset for scrolling
-	headerLine int        // Line number of header for scroll feature
-	max        int        // highest offset
+	width       int          // Current number of columns.
+	height      int          // Current number of rows.
+	cleared     bool         // True after the screens gets cleared.
+	layout      *Layout      // Pointer to layout (gets created by screen).
+	markup      *Markup      // Pointer to markup processor (gets created by screen).
+	pausedAt    *time.Time   // Timestamp of the pause request or nil if none.
+	profile     *Profile     // Pointer to profile passed to NewScreen
+	offset      int          // Offset for scrolling
+	headerLine  int          // Line number of header for scroll feature
+	max         int          // highest offset
+	marketLines int          // Number of lines taken by the market data.
+	status      map[int]bool // Status lines above the header that are not blank.
+	cursor      int          // Index of the selected stock quote.
+	selected    string       // Ticker of the selected stock quote to find it again after sorting.
+	follow      bool         // True when the cursor has moved and should be scrolled into view.
+	highlighted int          // Screen row highlighted as the cursor, -1 if none.
 }
 
 // Initializes Termbox, creates screen along with layout and markup, and
@@ -40,10 +46,19 @@ func NewScreen(profile *Profile) (*Screen, error) {
 	screen.markup = NewMarkup(profile)
 	screen.profile = profile
 	screen.offset = 0
+	screen.marketLines = profile.MarketLines()
+	screen.status = make(map[int]bool)
+	screen.highlighted = -1
 
 	return screen.Resize(), nil
 }
 
+// PromptLine returns the screen row between the watchlist tabs and the stock
+// quotes header where the line editor prompts for input.
+func (screen *Screen) PromptLine() int {
+	return screen.marketLines + 1
+}
+
 // Close gets called upon program termination to close the Termbox.
 func (screen *Screen) Close() *Screen {
 	termbox.Close()
@@ -113,12 +128,58 @@ func (screen *Screen) DecreaseOffset(n int) {
 
 func (screen *Screen) ScrollTop() {
 	screen.offset = 0
+	screen.selectStock(0)
 }
 
 func (screen *Screen) ScrollBottom() {
 	if screen.max > screen.height {
 		screen.offset = screen.max
 	}
+	screen.selectStock(len(screen.layout.stocks) - 1)
+	screen.follow = true
+}
+
+// MoveCursor moves the cursor n stock quotes down, or up if n is negative,
+// and scrolls the list on next update to keep the cursor row visible.
+func (screen *Screen) MoveCursor(n int) {
+	screen.selectStock(screen.cursor + n)
+	screen.follow = true
+}
+
+// SelectRow moves the cursor to the stock quote displayed at the given
+// screen row. It returns false if there is no stock quote at that row.
+func (screen *Screen) SelectRow(y int) bool {
+	rows := screen.layout.rows
+	line := y - screen.headerLine - 1 + screen.offset
+	if y <= screen.headerLine || line < 0 {
+		return false
+	}
+	for i := 0; i < len(rows)-1; i++ {
+		if line >= rows[i] && line < rows[i+1] {
+			screen.selectStock(i)
+			return true
+		}
+	}
+	return false
+}
+
+// GroupAt returns the name of the group whose header is displayed at the
+// given screen row, or blank string if there is none.
+func (screen *Screen) GroupAt(y int) string {
+	if y <= screen.headerLine {
+		return ``
+	}
+	return screen.layout.headers[y-screen.headerLine-1+screen.offset]
+}
+
+// Selected returns the stock quote under the cursor, or nil if the list of
+// stock quotes is empty.
+func (screen *Screen) Selected() *Stock {
+	if screen.cursor >= 0 && screen.cursor < len(screen.layout.stocks) {
+		stock := screen.layout.stocks[screen.cursor]
+		return &stock
+	}
+	return nil
 }
 
 func (screen *Screen) DrawOldQuotes(quotes *Quotes) {
@@ -127,8 +188,26 @@ func (screen *Screen) DrawOldQuotes(quotes *Quotes) {
 }
 
 func (screen *Screen) DrawOldMarket(market *Market) {
-	screen.draw(screen.layout.Market(market), false)
+	screen.drawMarket(market)
+	termbox.Flush()
+}
+
+// Flash briefly shows the screen in reverse video to draw user's attention.
+func (screen *Screen) Flash() *Screen {
+	cells := termbox.CellBuffer()
+	saved := make([]termbox.Cell, len(cells))
+	copy(saved, cells)
+
+	for i := range cells {
+		cells[i].Fg |= termbox.AttrReverse
+	}
+	termbox.Flush()
+	time.Sleep(150 * time.Millisecond)
+
+	copy(termbox.CellBuffer(), saved)
 	termbox.Flush()
+
+	return screen
 }
 
 // Draw accepts variable number of arguments and knows how to display the
@@ -145,7 +224,7 @@ func (screen *Screen) Draw(objects ...interface{}) *Screen {
 			if object.MarketData == nil {
 				object.Fetch()
 			}
-			screen.draw(screen.layout.Market(object), false)
+			screen.drawMarket(object)
 		case *Quotes:
 			object := ptr
 			if object.stocks == nil {
@@ -196,14 +275,14 @@ func (screen *Screen) DrawLineFlush(x, y int, str string, flush bool) {
 			} else {
 				start = screen.width - len(token) + i
 			}
-			if y%2 == 0 && y > 4 && screen.profile.RowShading {
+			if y%2 == 0 && y > screen.headerLine && screen.profile.RowShading {
 				termbox.SetCell(start, y, char, screen.markup.Foreground, screen.markup.RowShading)
 			} else {
 				termbox.SetCell(start, y, char, screen.markup.Foreground, screen.markup.Background)
 			}
 		}
 		if screen.profile.RowShading {
-			if start < screen.width && y%2 == 0 && y > 4 {
+			if start < screen.width && y%2 == 0 && y > screen.headerLine {
 				for i := start; i < screen.width; i++ {
 					start++
 					termbox.SetCell(start, y, ' ', termbox.ColorDefault, screen.markup.RowShading)
@@ -263,18 +342,27 @@ func (screen *Screen) draw(str string, offset bool) {
 	// Write the lines being updated.
 	for row := 0; row < len(allLines); row++ {
 		if offset {
-			// Did we draw the underlined heading row?  This is a crude
-			// check, but--see comments below...
+			// Did we draw the underlined heading row? It's the line that
+			// starts with the header markup whatever columns are shown.
 			// --- Heading row only appears for quotes, so offset is true
 			if !drewHeading {
-				if strings.Contains(allLines[row], "Ticker") &&
-					strings.Contains(allLines[row], "Last") &&
-					strings.Contains(allLines[row], "Change") {
+				if strings.HasPrefix(allLines[row], `<header>`) {
 					drewHeading = true
 					screen.headerLine = row
+					screen.scrollToCursor()
 					screen.DrawLine(0, row, allLines[row])
 					// move on to the point to offset to
 					row += screen.offset
+				} else if row >= screen.marketLines && (allLines[row] != `` || screen.status[row]) {
+					// Status line between the market data and the header,
+					// ex. watchlist tabs or alerts. Blank lines are left
+					// alone unless they were not blank before so that the
+					// line editor prompt doesn't get erased.
+					for x := 0; x < screen.width; x++ {
+						termbox.SetCell(x, row, ' ', termbox.ColorDefault, termbox.ColorDefault)
+					}
+					screen.DrawLineFlush(0, row, allLines[row], false)
+					screen.status[row] = allLines[row] != ``
 				}
 			} else {
 				// only write the necessary lines
@@ -307,5 +395,93 @@ func (screen *Screen) draw(str string, offset bool) {
 				screen.DrawLine(0, i, blankLine)
 			}
 		}
+		screen.highlight()
 	}
 }
+
+// Moves the cursor to the stock quote at the given index of the list shown
+// on the screen, and remembers its ticker.
+// -----------------------------------------------------------------------------
+func (screen *Screen) selectStock(i int) {
+	stocks := screen.layout.stocks
+	if i > len(stocks)-1 {
+		i = len(stocks) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	screen.cursor = i
+	if i < len(stocks) {
+		screen.selected = stocks[i].Ticker
+	}
+}
+
+// Keeps the cursor on the selected stock quote after the stock quotes have
+// been sorted again, or within the list if the stock is gone. If the cursor
+// has moved, adjusts the scroll offset so that the cursor row is visible.
+// -----------------------------------------------------------------------------
+func (screen *Screen) scrollToCursor() {
+	// The stock might be listed more than once, ex. in multiple tag groups,
+	// so the one closest to the cursor is picked.
+	found := -1
+	for i, stock := range screen.layout.stocks {
+		if stock.Ticker == screen.selected && (found < 0 || abs(i-screen.cursor) < abs(found-screen.cursor)) {
+			found = i
+		}
+	}
+	if found < 0 {
+		found = screen.cursor
+	}
+	screen.selectStock(found)
+
+	rows := screen.layout.rows
+	if !screen.follow || len(rows) < 2 {
+		return
+	}
+	screen.follow = false
+
+	// The extended hours row, if any, is kept in view along with the stock.
+	first, last := rows[screen.cursor], rows[screen.cursor+1]-1
+	visible := screen.height - screen.headerLine - 1
+	if first < screen.offset {
+		screen.offset = first
+	} else if last >= screen.offset+visible {
+		screen.offset = last - visible + 1
+	}
+}
+
+// Shows the cursor row in reverse video, and removes reverse video from the
+// row highlighted before since its trailing blanks don't get redrawn.
+// -----------------------------------------------------------------------------
+func (screen *Screen) highlight() {
+	cells := termbox.CellBuffer()
+	width, height := termbox.Size()
+	reverse := func(y int, on bool) {
+		for x := 0; x < width; x++ {
+			if on {
+				cells[y*width+x].Fg |= termbox.AttrReverse
+			} else {
+				cells[y*width+x].Fg &= ^termbox.AttrReverse
+			}
+		}
+	}
+
+	if screen.highlighted > screen.headerLine && screen.highlighted < height {
+		reverse(screen.highlighted, false)
+	}
+	screen.highlighted = -1
+	if rows := screen.layout.rows; screen.cursor < len(rows)-1 {
+		y := screen.headerLine + 1 + rows[screen.cursor] - screen.offset
+		if y > screen.headerLine && y < height {
+			reverse(y, true)
+			screen.highlighted = y
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+func (screen *Screen) drawMarket(market *Market) {
+	str := screen.layout.Market(market)
+	screen.marketLines = strings.Count(str, "\n") + 1
+	screen.draw(str, false)
+}
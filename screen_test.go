@@ -0,0 +1,47 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import "testing"
+
+func TestCursorFollowsSelectedStock(t *testing.T) {
+	arrange := func(layout *Layout, tickers ...string) {
+		layout.stocks, layout.rows = nil, nil
+		for i, ticker := range tickers {
+			layout.stocks = append(layout.stocks, Stock{Ticker: ticker})
+			layout.rows = append(layout.rows, i)
+		}
+		layout.rows = append(layout.rows, len(tickers))
+	}
+	screen := &Screen{layout: &Layout{}, height: 40}
+	arrange(screen.layout, `AAPL`, `MSFT`, `NVDA`)
+	screen.scrollToCursor()
+	screen.MoveCursor(1)
+
+	tests := []struct {
+		tickers  []string
+		cursor   int
+		selected string
+	}{
+		{[]string{`NVDA`, `AAPL`, `MSFT`}, 2, `MSFT`},         // Sorted again.
+		{[]string{`MSFT`, `AAPL`, `NVDA`, `MSFT`}, 3, `MSFT`}, // Listed twice, the closest one is picked.
+		{[]string{`AAPL`, `NVDA`}, 1, `NVDA`},                 // Gone, the cursor stays within the list.
+		{[]string{`AMZN`, `AAPL`, `GOOG`, `NVDA`}, 3, `NVDA`}, // Added.
+		{[]string{}, 0, `NVDA`},                               // Nothing to select.
+		{[]string{`GOOG`, `META`, `NVDA`, `AAPL`}, 2, `NVDA`}, // Back again.
+	}
+	for i, test := range tests {
+		arrange(screen.layout, test.tickers...)
+		screen.scrollToCursor()
+		if screen.cursor != test.cursor || screen.selected != test.selected {
+			t.Errorf(`#%d: got cursor %d at %s, want %d at %s`, i+1, screen.cursor, screen.selected, test.cursor, test.selected)
+		}
+	}
+
+	screen.MoveCursor(-10)
+	if stock := screen.Selected(); stock == nil || stock.Ticker != `GOOG` {
+		t.Errorf(`got selected %v, want GOOG`, stock)
+	}
+}
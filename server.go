@@ -0,0 +1,397 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server runs the same market data and stock quotes refresh loop as the
+// interactive mode but without the screen, and serves the latest data as
+// JSON over HTTP. All the clients share the cached data so that Yahoo gets
+// hit only once per refresh interval no matter how many clients there are.
+type Server struct {
+	sync.RWMutex
+	profile       *Profile       // Pointer to Profile.
+	market        *Market        // Pointer to Market.
+	quotes        *Quotes        // Pointer to Quotes.
+	provider      StockProvider  // Data provider, possibly collecting request metrics.
+	layout        *Layout        // Layout to filter and sort stock quotes.
+	marketUpdated time.Time      // When market data was fetched last time.
+	quotesUpdated time.Time      // When stock quotes were fetched last time.
+	refresh       chan bool      // Requests to fetch stock quotes right away.
+	mux           *http.ServeMux // API endpoints.
+}
+
+// Returns new Server for the given profile and data provider.
+func NewServer(profile *Profile, provider StockProvider) *Server {
+	market := NewMarket(profile, provider)
+	server := &Server{
+		profile:  profile,
+		market:   market,
+		quotes:   NewQuotes(market, profile, provider),
+		provider: provider,
+		layout:   NewLayout(),
+		refresh:  make(chan bool, 1),
+		mux:      http.NewServeMux(),
+	}
+	// Initialize the filter and sorter upfront rather than on the first
+	// request that arranges the stock quotes.
+	server.layout.filter = NewFilter(profile)
+	server.layout.sorter = NewSorter(profile)
+
+	server.mux.HandleFunc(`/api/market`, server.handleMarket)
+	server.mux.HandleFunc(`/api/quotes`, server.handleQuotes)
+	server.mux.HandleFunc(`/api/filter`, server.handleFilter)
+	server.mux.HandleFunc(`/api/tickers`, server.handleTickers)
+	server.mux.HandleFunc(`/api/errors`, server.handleErrors)
+	server.mux.HandleFunc(`/metrics`, server.handleMetrics)
+
+	return server
+}
+
+// Run fetches market data and stock quotes at the given intervals. Stock
+// quotes are also fetched right away whenever the list of tickers changes.
+// When the markets are closed the data is fetched every closedRefresh or
+// when they open; zero closedRefresh keeps the intervals fixed. Run never
+// returns so it's supposed to be called as a goroutine.
+func (server *Server) Run(marketRefresh, quotesRefresh, closedRefresh time.Duration) {
+	marketQueue := time.NewTicker(marketRefresh)
+	quotesQueue := time.NewTicker(quotesRefresh)
+	defer marketQueue.Stop()
+	defer quotesQueue.Stop()
+
+	// Both market data and stock quotes only get updated by this goroutine
+	// so there is no need to lock them to check when to refresh.
+	fetchMarket := func() {
+		server.fetchMarket()
+		if closedRefresh != 0 {
+			marketQueue.Reset(server.market.Refresh(marketRefresh, closedRefresh))
+		}
+	}
+	fetchQuotes := func() {
+		server.fetchQuotes()
+		if closedRefresh != 0 {
+			quotesQueue.Reset(server.quotes.Refresh(quotesRefresh, closedRefresh))
+		}
+	}
+
+	fetchMarket()
+	fetchQuotes()
+	for {
+		select {
+		case <-marketQueue.C:
+			fetchMarket()
+		case <-quotesQueue.C:
+			fetchQuotes()
+		case <-server.refresh:
+			fetchQuotes()
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler interface.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+// The market data and the stock quotes get fetched without holding the lock
+// so that the API requests are served meanwhile; only the results are set
+// under the lock.
+// -----------------------------------------------------------------------------
+func (server *Server) fetchMarket() {
+	server.RLock()
+	symbols := server.profile.MarketSymbols()
+	server.RUnlock()
+
+	marketData, err := server.provider.FetchMarket(symbols)
+
+	server.Lock()
+	defer server.Unlock()
+
+	server.market.update(marketData, err)
+	server.marketUpdated = time.Now()
+}
+
+// -----------------------------------------------------------------------------
+func (server *Server) fetchQuotes() {
+	server.RLock()
+	watchlist := server.profile.Watchlist
+	tickers := append([]string(nil), watchlist.Tickers...)
+	sparklines := !server.profile.Hidden(`Sparkline`)
+	server.RUnlock()
+
+	var stocks []Stock
+	var err error
+	if len(tickers) > 0 {
+		stocks, err = server.quotes.fetch(tickers, sparklines)
+	}
+
+	server.Lock()
+	fetched := len(tickers) > 0 && watchlist == server.profile.Watchlist
+	if fetched {
+		server.quotes.update(stocks, err)
+	}
+	server.quotesUpdated = time.Now()
+	server.Unlock()
+
+	// The alert notifiers might take a while, ex. webhooks, so the quotes
+	// are evaluated without the lock; they don't change till the next fetch.
+	if fetched && err == nil {
+		server.quotes.alerts.Evaluate(stocks)
+		server.Lock()
+		server.quotes.alerts.Save()
+		server.Unlock()
+	}
+}
+
+// GET /api/market returns the latest market data.
+// -----------------------------------------------------------------------------
+func (server *Server) handleMarket(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	server.RLock()
+	defer server.RUnlock()
+
+	_, err := server.market.Ok()
+	respond(w, http.StatusOK, map[string]interface{}{
+		`market`:  server.market.MarketData,
+		`updated`: server.marketUpdated,
+		`error`:   err,
+	})
+}
+
+// GET /api/quotes returns the latest stock quotes of the active watchlist
+// regardless of the filter, along with the portfolio totals.
+// -----------------------------------------------------------------------------
+func (server *Server) handleQuotes(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	server.RLock()
+	defer server.RUnlock()
+
+	_, err := server.quotes.Ok()
+	respond(w, http.StatusOK, map[string]interface{}{
+		`watchlist`: server.profile.Name,
+		`stocks`:    stocksOrEmpty(server.quotes.stocks),
+		`totals`:    totals(server.quotes.stocks),
+		`updated`:   server.quotesUpdated,
+		`error`:     err,
+	})
+}
+
+// GET /api/filter returns the stock quotes that pass the filter sorted the
+// same way as on the screen. PUT or POST {"filter": "..."} sets the filter,
+// and DELETE clears it.
+// -----------------------------------------------------------------------------
+func (server *Server) handleFilter(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		var request struct {
+			Filter string `json:"filter"`
+		}
+		if r.Method != http.MethodDelete {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				fail(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		// The filter that fails to compile is rejected, and the one in
+		// effect stays as it is.
+		server.Lock()
+		previous := server.profile.Filter
+		err := server.profile.SetFilter(strings.TrimSpace(request.Filter))
+		if err == nil {
+			err = server.profile.Save()
+		} else if server.profile.SetFilter(previous) != nil {
+			server.profile.SetFilter(``)
+		}
+		server.Unlock()
+		if err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Arranging the stock quotes isn't read-only: the filter that fails gets
+	// cleared, and the computed columns get compiled on first use.
+	server.Lock()
+	defer server.Unlock()
+
+	respond(w, http.StatusOK, map[string]interface{}{
+		`filter`:  server.profile.Filter,
+		`stocks`:  stocksOrEmpty(server.layout.arrange(server.quotes)),
+		`updated`: server.quotesUpdated,
+	})
+}
+
+// GET /api/tickers returns the tickers of the active watchlist. POST adds
+// and DELETE removes the tickers given as {"tickers": [...]} request body
+// or as comma-separated `tickers` query parameter.
+// -----------------------------------------------------------------------------
+func (server *Server) handleTickers(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		tickers, err := requestedTickers(r)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err)
+			return
+		}
+
+		server.Lock()
+		count := 0
+		if r.Method == http.MethodPost {
+			count, err = server.quotes.AddTickers(tickers)
+		} else {
+			count, err = server.quotes.RemoveTickers(tickers)
+		}
+		server.Unlock()
+		if err != nil {
+			fail(w, http.StatusInternalServerError, err)
+			return
+		}
+		if count > 0 {
+			select {
+			case server.refresh <- true:
+			default: // The refresh has been requested already.
+			}
+		}
+	}
+
+	server.RLock()
+	defer server.RUnlock()
+
+	respond(w, http.StatusOK, map[string]interface{}{
+		`watchlist`: server.profile.Name,
+		`tickers`:   server.profile.Tickers,
+	})
+}
+
+// GET /api/errors returns the errors, if any, that have occurred while
+// fetching the data or evaluating the alerts.
+// -----------------------------------------------------------------------------
+func (server *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	server.RLock()
+	defer server.RUnlock()
+
+	_, market := server.market.Ok()
+	_, quotes := server.quotes.Ok()
+	_, alerts := server.quotes.alerts.Ok()
+	respond(w, http.StatusOK, map[string]string{
+		`market`: market,
+		`quotes`: quotes,
+		`alerts`: alerts,
+	})
+}
+
+// GET /metrics returns stock quotes of the active watchlist, health of the
+// data fetches, and provider request metrics in Prometheus text format.
+// -----------------------------------------------------------------------------
+func (server *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set(`Content-Type`, `text/plain; version=0.0.4`)
+
+	server.RLock()
+	str := "# HELP mop_up Whether the last fetch has succeeded.\n# TYPE mop_up gauge\n"
+	for _, fetch := range []struct {
+		name string
+		ok   bool
+	}{
+		{`market`, server.market.errors == ``},
+		{`quotes`, server.quotes.errors == ``},
+	} {
+		up := 0
+		if fetch.ok {
+			up = 1
+		}
+		str += fmt.Sprintf("mop_up{fetch=%q} %d\n", fetch.name, up)
+	}
+	str += "# HELP mop_last_fetch_timestamp_seconds When the data was fetched last time.\n" +
+		"# TYPE mop_last_fetch_timestamp_seconds gauge\n" +
+		fmt.Sprintf("mop_last_fetch_timestamp_seconds{fetch=\"market\"} %d\n", server.marketUpdated.Unix()) +
+		fmt.Sprintf("mop_last_fetch_timestamp_seconds{fetch=\"quotes\"} %d\n", server.quotesUpdated.Unix())
+	io.WriteString(w, str)
+	writeStockMetrics(w, server.quotes.stocks)
+	server.RUnlock()
+
+	if provider, ok := server.provider.(Instrumented); ok && provider.Metrics() != nil {
+		provider.Metrics().WriteTo(w)
+	}
+}
+
+// -----------------------------------------------------------------------------
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set(`Allow`, strings.Join(methods, `, `))
+	fail(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+
+	return false
+}
+
+// -----------------------------------------------------------------------------
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// -----------------------------------------------------------------------------
+func fail(w http.ResponseWriter, status int, err error) {
+	respond(w, status, map[string]string{`error`: err.Error()})
+}
+
+// -----------------------------------------------------------------------------
+func requestedTickers(r *http.Request) ([]string, error) {
+	var request struct {
+		Tickers []string `json:"tickers"`
+	}
+	if query := r.URL.Query().Get(`tickers`); query != `` {
+		request.Tickers = strings.Split(query, `,`)
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, err
+	}
+
+	var tickers []string
+	for _, ticker := range request.Tickers {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != `` {
+			tickers = append(tickers, ticker)
+		}
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("no tickers given")
+	}
+
+	return tickers, nil
+}
+
+// -----------------------------------------------------------------------------
+func stocksOrEmpty(stocks []Stock) []Stock {
+	if stocks == nil {
+		return []Stock{}
+	}
+	return stocks
+}
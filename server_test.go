@@ -0,0 +1,125 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerAPI(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), `moprc`)
+	profile, err := NewProfile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile.Tickers = []string{`AAPL`, `MSFT`}
+	if err := profile.SetHolding(`AAPL`, &Holding{Shares: 10, Cost: 150}); err != nil {
+		t.Fatal(err)
+	}
+	provider := &stubProvider{stocks: []Stock{
+		{Ticker: `AAPL`, LastTrade: Float(190.5), Currency: `USD`},
+		{Ticker: `MSFT`, LastTrade: Float(410), Currency: `USD`},
+	}}
+	server := NewServer(profile, provider)
+	server.fetchQuotes()
+
+	type response struct {
+		Watchlist string
+		Filter    string
+		Stocks    []Stock
+		Totals    []Totals
+		Tickers   []string
+		Error     string
+	}
+	tests := []struct {
+		method  string
+		path    string
+		body    string
+		status  int
+		want    func(got response) bool
+		refresh bool // True if the request should trigger the refresh.
+		saved   string
+	}{
+		{http.MethodGet, `/api/quotes`, ``, http.StatusOK, func(got response) bool {
+			return got.Watchlist == `Default` && len(got.Stocks) == 2 && len(got.Totals) == 1 && got.Totals[0].Value == 1905
+		}, false, ``},
+		{http.MethodPatch, `/api/quotes`, ``, http.StatusMethodNotAllowed, func(got response) bool {
+			return got.Error == `method PATCH not allowed`
+		}, false, ``},
+		{http.MethodPut, `/api/filter`, `{"filter": " last > 200 "}`, http.StatusOK, func(got response) bool {
+			return got.Filter == `last > 200` && len(got.Stocks) == 1 && got.Stocks[0].Ticker == `MSFT`
+		}, false, `last > 200`},
+		{http.MethodPut, `/api/filter`, `{"filter": "lats > 100"}`, http.StatusBadRequest, func(got response) bool {
+			return got.Error == "unknown variable `lats` at position 1"
+		}, false, `last > 200`},
+		{http.MethodPost, `/api/filter`, `{"filter": "last >"}`, http.StatusBadRequest, func(got response) bool {
+			return got.Error != ``
+		}, false, `last > 200`},
+		{http.MethodPut, `/api/filter`, `last > 100`, http.StatusBadRequest, func(got response) bool {
+			return got.Error != ``
+		}, false, `last > 200`},
+		{http.MethodGet, `/api/filter`, ``, http.StatusOK, func(got response) bool {
+			return got.Filter == `last > 200` && len(got.Stocks) == 1 && got.Stocks[0].Ticker == `MSFT`
+		}, false, `last > 200`},
+		{http.MethodGet, `/api/quotes`, ``, http.StatusOK, func(got response) bool {
+			return len(got.Stocks) == 2 // The filter doesn't apply.
+		}, false, `last > 200`},
+		{http.MethodDelete, `/api/filter`, ``, http.StatusOK, func(got response) bool {
+			return got.Filter == `` && len(got.Stocks) == 2
+		}, false, ``},
+		{http.MethodPost, `/api/tickers?tickers=nvda,+aapl`, ``, http.StatusOK, func(got response) bool {
+			return reflect.DeepEqual(got.Tickers, []string{`AAPL`, `MSFT`, `NVDA`})
+		}, true, ``},
+		{http.MethodPost, `/api/tickers`, `{"tickers": ["AAPL"]}`, http.StatusOK, func(got response) bool {
+			return reflect.DeepEqual(got.Tickers, []string{`AAPL`, `MSFT`, `NVDA`})
+		}, false, ``},
+		{http.MethodDelete, `/api/tickers`, `{"tickers": ["msft", "IBM"]}`, http.StatusOK, func(got response) bool {
+			return reflect.DeepEqual(got.Tickers, []string{`AAPL`, `NVDA`})
+		}, true, ``},
+		{http.MethodPost, `/api/tickers`, `{"tickers": [" "]}`, http.StatusBadRequest, func(got response) bool {
+			return got.Error == `no tickers given`
+		}, false, ``},
+		{http.MethodGet, `/api/tickers`, ``, http.StatusOK, func(got response) bool {
+			return got.Watchlist == `Default` && reflect.DeepEqual(got.Tickers, []string{`AAPL`, `NVDA`})
+		}, false, ``},
+	}
+	for _, test := range tests {
+		name := test.method + ` ` + test.path + ` ` + test.body
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)))
+
+		var got response
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil {
+			t.Fatalf(`%s: %v`, name, err)
+		}
+		if recorder.Code != test.status || !test.want(got) {
+			t.Errorf(`%s: got %d %s`, name, recorder.Code, recorder.Body)
+		}
+
+		refreshed := false
+		select {
+		case <-server.refresh:
+			refreshed = true
+		default:
+		}
+		if refreshed != test.refresh {
+			t.Errorf(`%s: got refresh %v, want %v`, name, refreshed, test.refresh)
+		}
+
+		saved, err := NewProfile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Filter != test.saved || profile.Filter != test.saved {
+			t.Errorf(`%s: got filter %q, saved %q, want %q`, name, profile.Filter, saved.Filter, test.saved)
+		}
+	}
+}
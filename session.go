@@ -0,0 +1,216 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kinds of provider calls captured in the session archive.
+const (
+	sessionMarket = `market`
+	sessionQuotes = `quotes`
+)
+
+// sessionEvent stores the outcome of a single provider call. The session
+// archive is a file with one JSON encoded event per line.
+type sessionEvent struct {
+	Time    time.Time   `json:"time"`              // When the call has completed.
+	Kind    string      `json:"kind"`              // Either `market` or `quotes`.
+	Tickers []string    `json:"tickers,omitempty"` // Requested tickers (quotes only).
+	Market  *MarketData `json:"market,omitempty"`  // Fetched market data.
+	Stocks  []Stock     `json:"stocks,omitempty"`  // Fetched stock quotes.
+	Error   string      `json:"error,omitempty"`   // Error returned by the provider, if any.
+}
+
+// RecordingProvider wraps another StockProvider and appends the result of
+// every FetchMarket and FetchQuotes call to the session archive.
+type RecordingProvider struct {
+	sync.Mutex
+	provider StockProvider // Provider doing the actual work.
+	file     *os.File      // Session archive.
+	encoder  *json.Encoder // Encoder writing events to the archive.
+}
+
+// NewRecordingProvider creates the session archive and returns the provider
+// that records all the calls to the given one.
+func NewRecordingProvider(provider StockProvider, filename string) (*RecordingProvider, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordingProvider{
+		provider: provider,
+		file:     file,
+		encoder:  json.NewEncoder(file),
+	}, nil
+}
+
+// FetchMarket fetches market data from the wrapped provider and records it.
+func (recorder *RecordingProvider) FetchMarket(symbols []string) (*MarketData, error) {
+	market, err := recorder.provider.FetchMarket(symbols)
+	recorder.record(&sessionEvent{Kind: sessionMarket, Market: market}, err)
+
+	return market, err
+}
+
+// FetchQuotes fetches stock quotes from the wrapped provider and records them.
+func (recorder *RecordingProvider) FetchQuotes(tickers []string) ([]Stock, error) {
+	stocks, err := recorder.provider.FetchQuotes(tickers)
+	recorder.record(&sessionEvent{Kind: sessionQuotes, Tickers: tickers, Stocks: stocks}, err)
+
+	return stocks, err
+}
+
+// FetchChart fetches the chart from the wrapped provider. The charts are not
+// recorded.
+func (recorder *RecordingProvider) FetchChart(ticker, span string) (*Chart, error) {
+	if provider, ok := recorder.provider.(ChartProvider); ok {
+		return provider.FetchChart(ticker, span)
+	}
+	return nil, errNoCharts
+}
+
+// Metrics returns request metrics of the recorded provider or nil if the
+// provider doesn't collect them.
+func (recorder *RecordingProvider) Metrics() *Metrics {
+	if provider, ok := recorder.provider.(Instrumented); ok {
+		return provider.Metrics()
+	}
+	return nil
+}
+
+// Close flushes and closes the session archive.
+func (recorder *RecordingProvider) Close() error {
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	return recorder.file.Close()
+}
+
+// -----------------------------------------------------------------------------
+func (recorder *RecordingProvider) record(event *sessionEvent, err error) {
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	event.Time = time.Now()
+	if err != nil {
+		event.Error = err.Error()
+	}
+	recorder.encoder.Encode(event) // Recording is best effort and must not break the display.
+}
+
+// PlaybackProvider implements StockProvider by replaying the session archive
+// written by RecordingProvider. The recorded timeline is replayed at given
+// speed: every fetch returns the latest recorded result of its kind as of
+// the current playback time.
+type PlaybackProvider struct {
+	events  []sessionEvent // Recorded events in chronological order.
+	speed   float64        // Playback speed, 1.0 being real time.
+	started time.Time      // When the playback has started.
+	skip    time.Duration  // Offset into the session to start playback from.
+}
+
+// NewPlaybackProvider loads the session archive. The playback begins at
+// `skip` into the recorded session and runs `speed` times faster than real
+// time.
+func NewPlaybackProvider(filename string, speed float64, skip time.Duration) (*PlaybackProvider, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	playback := &PlaybackProvider{speed: speed, skip: skip, started: time.Now()}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		event := sessionEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+		}
+		playback.events = append(playback.events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(playback.events) == 0 {
+		return nil, fmt.Errorf("%s: empty session", filename)
+	}
+	if playback.speed <= 0 {
+		playback.speed = 1.0
+	}
+
+	return playback, nil
+}
+
+// FetchMarket returns the market data recorded as of current playback time.
+// The symbols are ignored: the market summary shows the symbols that were
+// recorded and are still requested.
+func (playback *PlaybackProvider) FetchMarket(symbols []string) (*MarketData, error) {
+	event := playback.latest(sessionMarket)
+	if event == nil {
+		return nil, errors.New(`no market data recorded in the session`)
+	}
+	if event.Error != `` {
+		return nil, errors.New(event.Error)
+	}
+
+	return event.Market, nil
+}
+
+// FetchQuotes returns the stock quotes recorded as of current playback time.
+// The list of requested tickers is ignored so that the quotes are replayed
+// exactly as they were recorded.
+func (playback *PlaybackProvider) FetchQuotes(tickers []string) ([]Stock, error) {
+	event := playback.latest(sessionQuotes)
+	if event == nil {
+		return nil, errors.New(`no stock quotes recorded in the session`)
+	}
+	if event.Error != `` {
+		return nil, errors.New(event.Error)
+	}
+
+	return event.Stocks, nil
+}
+
+// now returns the recorded wall clock time matching current playback time.
+func (playback *PlaybackProvider) now() time.Time {
+	return playback.events[0].Time.Add(playback.elapsed())
+}
+
+// -----------------------------------------------------------------------------
+func (playback *PlaybackProvider) elapsed() time.Duration {
+	return playback.skip + time.Duration(float64(time.Since(playback.started))*playback.speed)
+}
+
+// latest returns the last event of the given kind recorded at or before
+// current playback time. If there is none yet the first event of the kind
+// is returned so that the screen is not left blank.
+func (playback *PlaybackProvider) latest(kind string) *sessionEvent {
+	var found *sessionEvent
+
+	now := playback.now()
+	for i := range playback.events {
+		event := &playback.events[i]
+		if event.Kind != kind {
+			continue
+		}
+		if found == nil || !event.Time.After(now) {
+			found = event
+		} else {
+			break
+		}
+	}
+
+	return found
+}
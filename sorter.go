@@ -5,197 +5,56 @@
 package mop
 
 import (
+	"reflect"
 	"sort"
-	"strconv"
 	"strings"
 )
 
-// Sorter gets called to sort stock quotes by one of the columns. The
-// setup is rather lengthy; there should probably be more concise way
-// that uses reflection and avoids hardcoding the column names.
+// Sorter gets called to sort stock quotes by one or more sort keys. The
+// keys are looked up by name so that the stock quotes can be sorted by any
+// column, including Yahoo quote fields and computed columns, as well as by
+// the filter variables such as market.
 type Sorter struct {
 	profile *Profile // Pointer to where we store sort column and order.
 }
 
-type sortable []Stock
-
-func (list sortable) Len() int      { return len(list) }
-func (list sortable) Swap(i, j int) { list[i], list[j] = list[j], list[i] }
-
-type (
-	byTickerAsc     struct{ sortable }
-	byLastTradeAsc  struct{ sortable }
-	byChangeAsc     struct{ sortable }
-	byChangePctAsc  struct{ sortable }
-	byOpenAsc       struct{ sortable }
-	byLowAsc        struct{ sortable }
-	byHighAsc       struct{ sortable }
-	byLow52Asc      struct{ sortable }
-	byHigh52Asc     struct{ sortable }
-	byVolumeAsc     struct{ sortable }
-	byAvgVolumeAsc  struct{ sortable }
-	byPeRatioAsc    struct{ sortable }
-	byDividendAsc   struct{ sortable }
-	byYieldAsc      struct{ sortable }
-	byMarketCapAsc  struct{ sortable }
-	byPreOpenAsc    struct{ sortable }
-	byAfterHoursAsc struct{ sortable }
-)
-
-type (
-	byTickerDesc     struct{ sortable }
-	byLastTradeDesc  struct{ sortable }
-	byChangeDesc     struct{ sortable }
-	byChangePctDesc  struct{ sortable }
-	byOpenDesc       struct{ sortable }
-	byLowDesc        struct{ sortable }
-	byHighDesc       struct{ sortable }
-	byLow52Desc      struct{ sortable }
-	byHigh52Desc     struct{ sortable }
-	byVolumeDesc     struct{ sortable }
-	byAvgVolumeDesc  struct{ sortable }
-	byPeRatioDesc    struct{ sortable }
-	byDividendDesc   struct{ sortable }
-	byYieldDesc      struct{ sortable }
-	byMarketCapDesc  struct{ sortable }
-	byPreOpenDesc    struct{ sortable }
-	byAfterHoursDesc struct{ sortable }
-)
-
-func (list byTickerAsc) Less(i, j int) bool {
-	return list.sortable[i].Ticker < list.sortable[j].Ticker
-}
-
-func (list byLastTradeAsc) Less(i, j int) bool {
-	return list.sortable[i].LastTrade < list.sortable[j].LastTrade
-}
-
-func (list byChangeAsc) Less(i, j int) bool {
-	return c(list.sortable[i].Change) < c(list.sortable[j].Change)
-}
-
-func (list byChangePctAsc) Less(i, j int) bool {
-	return c(list.sortable[i].ChangePct) < c(list.sortable[j].ChangePct)
-}
-
-func (list byOpenAsc) Less(i, j int) bool {
-	return list.sortable[i].Open < list.sortable[j].Open
-}
-
-func (list byLowAsc) Less(i, j int) bool {
-	return list.sortable[i].Low < list.sortable[j].Low
-}
-
-func (list byHighAsc) Less(i, j int) bool {
-	return list.sortable[i].High < list.sortable[j].High
-}
-
-func (list byLow52Asc) Less(i, j int) bool {
-	return list.sortable[i].Low52 < list.sortable[j].Low52
-}
-
-func (list byHigh52Asc) Less(i, j int) bool {
-	return list.sortable[i].High52 < list.sortable[j].High52
+// SortKey is the column or the filter variable to sort stock quotes by along
+// with the sort direction.
+type SortKey struct {
+	Column     string // Column name, ex. ChangePct, or filter variable, ex. market.
+	Descending bool   `json:",omitempty"` // True when sort order is descending.
 }
 
-func (list byVolumeAsc) Less(i, j int) bool {
-	return m(list.sortable[i].Volume) < m(list.sortable[j].Volume)
-}
-
-func (list byAvgVolumeAsc) Less(i, j int) bool {
-	return m(list.sortable[i].AvgVolume) < m(list.sortable[j].AvgVolume)
-}
-
-func (list byPeRatioAsc) Less(i, j int) bool {
-	return list.sortable[i].PeRatio < list.sortable[j].PeRatio
-}
-
-func (list byDividendAsc) Less(i, j int) bool {
-	return list.sortable[i].Dividend < list.sortable[j].Dividend
-}
-
-func (list byYieldAsc) Less(i, j int) bool {
-	return list.sortable[i].Yield < list.sortable[j].Yield
-}
-
-func (list byMarketCapAsc) Less(i, j int) bool {
-	return m(list.sortable[i].MarketCap) < m(list.sortable[j].MarketCap)
-}
-
-func (list byPreOpenAsc) Less(i, j int) bool {
-	return c(list.sortable[i].PreOpen) < c(list.sortable[j].PreOpen)
-}
-
-func (list byAfterHoursAsc) Less(i, j int) bool {
-	return c(list.sortable[i].AfterHours) < c(list.sortable[j].AfterHours)
-}
-
-func (list byTickerDesc) Less(i, j int) bool {
-	return list.sortable[j].Ticker < list.sortable[i].Ticker
-}
-
-func (list byLastTradeDesc) Less(i, j int) bool {
-	return list.sortable[j].LastTrade < list.sortable[i].LastTrade
-}
-
-func (list byChangeDesc) Less(i, j int) bool {
-	return c(list.sortable[j].Change) < c(list.sortable[i].Change)
-}
-
-func (list byChangePctDesc) Less(i, j int) bool {
-	return c(list.sortable[j].ChangePct) < c(list.sortable[i].ChangePct)
-}
-
-func (list byOpenDesc) Less(i, j int) bool {
-	return list.sortable[j].Open < list.sortable[i].Open
-}
-
-func (list byLowDesc) Less(i, j int) bool {
-	return list.sortable[j].Low < list.sortable[i].Low
-}
-
-func (list byHighDesc) Less(i, j int) bool {
-	return list.sortable[j].High < list.sortable[i].High
-}
-
-func (list byLow52Desc) Less(i, j int) bool {
-	return list.sortable[j].Low52 < list.sortable[i].Low52
-}
-
-func (list byHigh52Desc) Less(i, j int) bool {
-	return list.sortable[j].High52 < list.sortable[i].High52
-}
-
-func (list byVolumeDesc) Less(i, j int) bool {
-	return m(list.sortable[j].Volume) < m(list.sortable[i].Volume)
-}
-
-func (list byAvgVolumeDesc) Less(i, j int) bool {
-	return m(list.sortable[j].AvgVolume) < m(list.sortable[i].AvgVolume)
-}
-
-func (list byPeRatioDesc) Less(i, j int) bool {
-	return list.sortable[j].PeRatio < list.sortable[i].PeRatio
-}
-
-func (list byDividendDesc) Less(i, j int) bool {
-	return list.sortable[j].Dividend < list.sortable[i].Dividend
-}
+// Placement of the values that are not available, ex. P/E of the stock
+// that has no earnings.
+const (
+	NALow   = ``      // Sorted as the lowest values, i.e. first when ascending and last when descending.
+	NAFirst = `first` // Always first.
+	NALast  = `last`  // Always last.
+)
 
-func (list byYieldDesc) Less(i, j int) bool {
-	return list.sortable[j].Yield < list.sortable[i].Yield
+// byKeys sorts stock quotes by the values of the sort keys looked up upfront.
+type byKeys struct {
+	stocks    []Stock         // Stock quotes to sort.
+	values    [][]interface{} // Values of the sort keys, one list per stock.
+	keys      []SortKey       // Sort keys, most significant first.
+	placement string          // Placement of the values that are not available.
 }
 
-func (list byMarketCapDesc) Less(i, j int) bool {
-	return m(list.sortable[j].MarketCap) < m(list.sortable[i].MarketCap)
-}
+func (list byKeys) Len() int { return len(list.stocks) }
 
-func (list byPreOpenDesc) Less(i, j int) bool {
-	return c(list.sortable[j].PreOpen) < c(list.sortable[i].PreOpen)
+func (list byKeys) Swap(i, j int) {
+	list.stocks[i], list.stocks[j] = list.stocks[j], list.stocks[i]
+	list.values[i], list.values[j] = list.values[j], list.values[i]
 }
 
-func (list byAfterHoursDesc) Less(i, j int) bool {
-	return c(list.sortable[j].AfterHours) < c(list.sortable[i].AfterHours)
+func (list byKeys) Less(i, j int) bool {
+	for k, key := range list.keys {
+		if result := compare(list.values[i][k], list.values[j][k], key.Descending, list.placement); result != 0 {
+			return result < 0
+		}
+	}
+	return false
 }
 
 // Returns new Sorter struct.
@@ -205,94 +64,112 @@ func NewSorter(profile *Profile) *Sorter {
 	}
 }
 
-// SortByCurrentColumn builds a list of sort interface based on current sort
-// order, then calls sort.Sort to do the actual job.
-func (sorter *Sorter) SortByCurrentColumn(stocks []Stock) *Sorter {
-	var interfaces []sort.Interface
-
-	if sorter.profile.Ascending {
-		interfaces = []sort.Interface{
-			byTickerAsc{stocks},
-			byLastTradeAsc{stocks},
-			byChangeAsc{stocks},
-			byChangePctAsc{stocks},
-			byOpenAsc{stocks},
-			byLowAsc{stocks},
-			byHighAsc{stocks},
-			byLow52Asc{stocks},
-			byHigh52Asc{stocks},
-			byVolumeAsc{stocks},
-			byAvgVolumeAsc{stocks},
-			byPeRatioAsc{stocks},
-			byDividendAsc{stocks},
-			byYieldAsc{stocks},
-			byMarketCapAsc{stocks},
-			byPreOpenAsc{stocks},
-			byAfterHoursAsc{stocks},
-		}
-	} else {
-		interfaces = []sort.Interface{
-			byTickerDesc{stocks},
-			byLastTradeDesc{stocks},
-			byChangeDesc{stocks},
-			byChangePctDesc{stocks},
-			byOpenDesc{stocks},
-			byLowDesc{stocks},
-			byHighDesc{stocks},
-			byLow52Desc{stocks},
-			byHigh52Desc{stocks},
-			byVolumeDesc{stocks},
-			byAvgVolumeDesc{stocks},
-			byPeRatioDesc{stocks},
-			byDividendDesc{stocks},
-			byYieldDesc{stocks},
-			byMarketCapDesc{stocks},
-			byPreOpenDesc{stocks},
-			byAfterHoursDesc{stocks},
-		}
+// Sort sorts stock quotes by the given keys: the stocks with equal values of
+// the first key get sorted by the second key, and so on. The sort is stable,
+// i.e. the stocks that are equal by all the keys keep their order.
+func (sorter *Sorter) Sort(stocks []Stock, keys []SortKey) *Sorter {
+	if len(keys) == 0 {
+		return sorter
 	}
-
-	sort.Sort(interfaces[sorter.profile.SortColumn])
+	values := make([][]interface{}, len(stocks))
+	for i := range stocks {
+		values[i] = sortValues(&stocks[i], keys)
+	}
+	sort.Stable(byKeys{stocks, values, keys, sorter.profile.SortNA})
 
 	return sorter
 }
 
-// The same exact method is used to sort by $Change and Change%. In both cases
-// we sort by the value of Change% so that multiple $0.00s get sorted properly.
-func c(str string) float32 {
-	c := "$"
-	for _, v := range currencies {
-		if strings.Contains(str, v) {
-			c = v
+// Returns the values of the sort keys for the given stock. The keys are
+// looked up among the columns first, then among the filter variables.
+// -----------------------------------------------------------------------------
+func sortValues(stock *Stock, keys []SortKey) []interface{} {
+	var variables map[string]interface{}
+	values := make([]interface{}, len(keys))
+	for k, key := range keys {
+		if key.Column == `Sparkline` {
+			values[k] = stock.trend() // Sort by the change since the first price of the day.
+		} else if _, ok := reflect.TypeOf(*stock).FieldByName(key.Column); ok || optionalColumn(key.Column) {
+			values[k] = stock.value(key.Column)
+		} else if value, ok := stock.Computed[key.Column]; ok {
+			values[k] = value
+		} else {
+			if variables == nil {
+				variables = filterValues(stock)
+			}
+			if value, ok := variables[key.Column]; ok {
+				values[k] = value
+			} else {
+				values[k] = stock.Fields[key.Column]
+			}
 		}
 	}
-	trimmed := strings.Replace(strings.Trim(str, ` %`), c, ``, 1)
-	value, _ := strconv.ParseFloat(trimmed, 32)
-	return float32(value)
-}
-
-// When sorting by the market value we must first convert 42B etc. notations
-// to proper numeric values.
-func m(str string) float32 {
-	if len(str) == 0 {
-		return 0
+	return values
+}
+
+// Compares two values of the sort key and returns negative number if the
+// first value goes before the second one, positive number if it goes after,
+// or zero if they are equal. Blank strings and missing values are not
+// available and get placed as requested.
+// -----------------------------------------------------------------------------
+func compare(a, b interface{}, descending bool, placement string) int {
+	x, xok := sortable(a)
+	y, yok := sortable(b)
+	if !xok || !yok {
+		switch {
+		case xok == yok:
+			return 0
+		case placement == NAFirst:
+			return order(!xok)
+		case placement == NALast:
+			return order(xok)
+		}
+		return order(xok == descending) // The values that are not available are the lowest ones.
 	}
 
-	multiplier := 1.0
-
-	switch str[len(str)-1:] { // Check the last character.
-	case `T`:
-		multiplier = 1000000000000.0
-	case `B`:
-		multiplier = 1000000000.0
-	case `M`:
-		multiplier = 1000000.0
-	case `K`:
-		multiplier = 1000.0
+	result := 0
+	if xs, ok := x.(string); ok {
+		ys, _ := y.(string)
+		result = strings.Compare(xs, ys)
+	} else {
+		xf, _ := x.(float64)
+		yf, _ := y.(float64)
+		if xf < yf {
+			result = -1
+		} else if xf > yf {
+			result = 1
+		}
 	}
+	if descending {
+		return -result
+	}
+	return result
+}
 
-	trimmed := strings.Trim(str, ` $TBMK`) // Get rid of non-numeric characters.
-	value, _ := strconv.ParseFloat(trimmed, 32)
-
-	return float32(value * multiplier)
+// Returns -1 if the first value goes before the second one, or 1 otherwise.
+// -----------------------------------------------------------------------------
+func order(before bool) int {
+	if before {
+		return -1
+	}
+	return 1
+}
+
+// Returns the value of the sort key as either string or float64, and false
+// if the value is not available. The strings and numbers are never compared
+// to each other: a string is always the same column or variable as another
+// string.
+// -----------------------------------------------------------------------------
+func sortable(value interface{}) (interface{}, bool) {
+	switch value := value.(type) {
+	case string:
+		return value, value != ``
+	case bool:
+		if value {
+			return 1.0, true
+		}
+		return 0.0, true
+	}
+	v, ok := number(value)
+	return v, ok
 }
@@ -0,0 +1,144 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSorterSort(t *testing.T) {
+	stocks := []Stock{
+		{Ticker: `A`, PeRatio: Float(10), Change: Float(1)},
+		{Ticker: `B`, Change: Float(2)},
+		{Ticker: `C`, PeRatio: Float(5), Change: Float(1)},
+		{Ticker: `D`, Change: Float(1)},
+		{Ticker: `E`, PeRatio: Float(10), Change: Float(2)},
+	}
+	pe := SortKey{Column: `PeRatio`}
+	peDown := SortKey{Column: `PeRatio`, Descending: true}
+	changeDown := SortKey{Column: `Change`, Descending: true}
+
+	tests := []struct {
+		name   string
+		keys   []SortKey
+		sortNA string
+		want   string
+	}{
+		{`no keys`, nil, NALow, `A B C D E`},
+		{`ascending`, []SortKey{pe}, NALow, `B D C A E`},
+		{`descending`, []SortKey{peDown}, NALow, `A E C B D`},
+		{`ascending n/a first`, []SortKey{pe}, NAFirst, `B D C A E`},
+		{`descending n/a first`, []SortKey{peDown}, NAFirst, `B D A E C`},
+		{`ascending n/a last`, []SortKey{pe}, NALast, `C A E B D`},
+		{`descending n/a last`, []SortKey{peDown}, NALast, `A E C B D`},
+		{`mixed directions`, []SortKey{changeDown, pe}, NALow, `B E D C A`},
+		{`mixed directions n/a last`, []SortKey{changeDown, pe}, NALast, `E B C A D`},
+		{`mixed directions n/a first`, []SortKey{changeDown, peDown}, NAFirst, `B E D A C`},
+		{`strings`, []SortKey{{Column: `Ticker`, Descending: true}}, NALow, `E D C B A`},
+		{`filter variable`, []SortKey{{Column: `change`}, {Column: `ticker`, Descending: true}}, NALow, `D C A E B`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile := &Profile{Watchlist: &Watchlist{SortNA: test.sortNA}}
+			sorted := append([]Stock(nil), stocks...)
+			NewSorter(profile).Sort(sorted, test.keys)
+
+			var tickers []string
+			for _, stock := range sorted {
+				tickers = append(tickers, stock.Ticker)
+			}
+			if got := strings.Join(tickers, ` `); got != test.want {
+				t.Errorf(`got %s, want %s`, got, test.want)
+			}
+		})
+	}
+}
+
+func TestProfileReorder(t *testing.T) {
+	tests := []struct {
+		name      string
+		column    string
+		selected  int
+		watchlist Watchlist
+		want      Watchlist
+	}{
+		{
+			name:      `reverse the sort column`,
+			column:    `Change`,
+			selected:  2,
+			watchlist: Watchlist{SortColumn: 2, Ascending: true},
+			want:      Watchlist{SortColumn: 2, Ascending: false},
+		},
+		{
+			name:      `pick another sort column`,
+			column:    `PeRatio`,
+			selected:  9,
+			watchlist: Watchlist{SortColumn: 2, Ascending: true},
+			want:      Watchlist{SortColumn: 9, Ascending: true},
+		},
+		{
+			name:      `reverse the first sort key`,
+			column:    `Change`,
+			selected:  2,
+			watchlist: Watchlist{SortColumn: 2, SortKeys: []SortKey{{Column: `Change`, Descending: true}, {Column: `PeRatio`}}},
+			want:      Watchlist{SortColumn: 2, Ascending: true, SortKeys: []SortKey{{Column: `Change`}, {Column: `PeRatio`}}},
+		},
+		{
+			name:      `secondary sort key becomes the first one`,
+			column:    `PeRatio`,
+			selected:  9,
+			watchlist: Watchlist{SortColumn: 2, SortKeys: []SortKey{{Column: `Change`, Descending: true}, {Column: `PeRatio`}}},
+			want:      Watchlist{SortColumn: 9, SortKeys: []SortKey{{Column: `PeRatio`, Descending: true}, {Column: `Change`, Descending: true}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			watchlist := test.watchlist
+			profile := &Profile{Watchlist: &watchlist, filename: filepath.Join(t.TempDir(), `moprc`), selectedColumn: test.selected}
+			if err := profile.Reorder(test.column); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(watchlist, test.want) {
+				t.Errorf("got  %+v\nwant %+v", watchlist, test.want)
+			}
+		})
+	}
+}
+
+func TestProfileThenSortBy(t *testing.T) {
+	profile := &Profile{Watchlist: &Watchlist{}, filename: filepath.Join(t.TempDir(), `moprc`)}
+	first := []SortKey{{Column: `Change`, Descending: true}}
+
+	tests := []struct {
+		keys   []SortKey
+		column string
+		want   []SortKey
+	}{
+		{nil, `PeRatio`, nil},  // No sort keys to follow.
+		{first, `Change`, nil}, // Already the sort column.
+		{first, `PeRatio`, []SortKey{first[0], {Column: `PeRatio`}}},
+		{nil, `PeRatio`, []SortKey{first[0], {Column: `PeRatio`, Descending: true}}},
+		{nil, `Volume`, []SortKey{first[0], {Column: `PeRatio`, Descending: true}, {Column: `Volume`}}},
+		{nil, `PeRatio`, []SortKey{first[0], {Column: `Volume`}}},
+	}
+	for i, test := range tests {
+		keys := test.keys
+		if keys == nil {
+			keys = profile.SortKeys
+		}
+		if err := profile.ThenSortBy(keys, test.column); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(profile.SortKeys, test.want) {
+			t.Errorf(`step %d: got %v, want %v`, i+1, profile.SortKeys, test.want)
+		}
+	}
+	if !reflect.DeepEqual(first, []SortKey{{Column: `Change`, Descending: true}}) {
+		t.Errorf(`the given keys have been changed: %v`, first)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// NullFloat is a float64 value that might be not available (N/A), for
+// example the P/E ratio of a company with negative earnings.
+type NullFloat struct {
+	Value float64 // The value itself; zero when not available.
+	Valid bool    // True when the value is available.
+}
+
+// NullInt is an int64 value that might be not available (N/A).
+type NullInt struct {
+	Value int64 // The value itself; zero when not available.
+	Valid bool  // True when the value is available.
+}
+
+// Float returns available float64 value.
+func Float(value float64) NullFloat {
+	return NullFloat{Value: value, Valid: true}
+}
+
+// Int returns available int64 value.
+func Int(value int64) NullInt {
+	return NullInt{Value: value, Valid: true}
+}
+
+// Less reports whether the value sorts before the other one. Values that
+// are not available sort before all the others.
+func (f NullFloat) Less(other NullFloat) bool {
+	if !f.Valid || !other.Valid {
+		return !f.Valid && other.Valid
+	}
+	return f.Value < other.Value
+}
+
+// Less reports whether the value sorts before the other one. Values that
+// are not available sort before all the others.
+func (i NullInt) Less(other NullInt) bool {
+	if !i.Valid || !other.Valid {
+		return !i.Valid && other.Valid
+	}
+	return i.Value < other.Value
+}
+
+// Float returns the value as NullFloat.
+func (i NullInt) Float() NullFloat {
+	return NullFloat{Value: float64(i.Value), Valid: i.Valid}
+}
+
+// MarshalJSON encodes the value as a JSON number, or null if the value is
+// not available.
+func (f NullFloat) MarshalJSON() ([]byte, error) {
+	if !f.Valid {
+		return []byte(`null`), nil
+	}
+	return json.Marshal(f.Value)
+}
+
+// UnmarshalJSON decodes JSON number or null.
+func (f *NullFloat) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte(`null`)) {
+		*f = NullFloat{}
+		return nil
+	}
+	f.Valid = true
+	return json.Unmarshal(data, &f.Value)
+}
+
+// MarshalJSON encodes the value as a JSON number, or null if the value is
+// not available.
+func (i NullInt) MarshalJSON() ([]byte, error) {
+	if !i.Valid {
+		return []byte(`null`), nil
+	}
+	return json.Marshal(i.Value)
+}
+
+// UnmarshalJSON decodes JSON number or null.
+func (i *NullInt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte(`null`)) {
+		*i = NullInt{}
+		return nil
+	}
+	i.Valid = true
+	return json.Unmarshal(data, &i.Value)
+}
+
+// floatField extracts numeric value from decoded Yahoo quote result.
+func floatField(result map[string]interface{}, key string) NullFloat {
+	if value, ok := result[key].(float64); ok {
+		return Float(value)
+	}
+	return NullFloat{}
+}
+
+// intField extracts integer value from decoded Yahoo quote result.
+func intField(result map[string]interface{}, key string) NullInt {
+	if value, ok := result[key].(float64); ok {
+		return Int(int64(value))
+	}
+	return NullInt{}
+}
+
+// timeField extracts Unix timestamp from decoded Yahoo quote result. The
+// zero time is returned if the timestamp is not available.
+func timeField(result map[string]interface{}, key string) time.Time {
+	if value, ok := result[key].(float64); ok && value > 0 {
+		return time.Unix(int64(value), 0)
+	}
+	return time.Time{}
+}
+
+// stringField extracts string value from decoded Yahoo quote result.
+func stringField(result map[string]interface{}, key string) string {
+	if value, ok := result[key].(string); ok {
+		return value
+	}
+	return ``
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		format func(interface{}, string) string
+		value  interface{}
+		code   string
+		want   string
+	}{
+		{`currency`, currency, Float(331.76), `USD`, `$331.76`},
+		{`currency`, currency, Float(0.0123), `USD`, `$0.0123`},
+		{`currency`, currency, Float(-0.5), `USD`, `-$0.5000`},
+		{`currency`, currency, Float(0), `USD`, `$0.00`},
+		{`currency`, currency, Float(999.999), `USD`, `$1000.00`},
+		{`currency`, currency, Float(1234567), `EUR`, `€1.23M`},
+		{`currency`, currency, Float(-2.5e12), `JPY`, `-¥2.50T`},
+		{`currency`, currency, Float(14.5), `XYZ`, `$14.50`},
+		{`currency`, currency, NullFloat{}, `USD`, `-`},
+		{`zero`, zero, Float(0), `USD`, `-`},
+		{`zero`, zero, Float(0.05), `GBp`, `p0.0500`},
+		{`zero`, zero, NullFloat{}, `USD`, `-`},
+		{`percent`, percent, Float(-1.23456), ``, `-1.23%`},
+		{`percent`, percent, NullFloat{}, ``, `-`},
+		{`blank`, blank, Float(18.946888), ``, `18.95`},
+		{`blank`, blank, NullFloat{}, ``, `-`},
+		{`integer`, integer, Int(999), ``, `999`},
+		{`integer`, integer, Int(1200), ``, `1200`},
+		{`integer`, integer, Int(100000), ``, `100000`},
+		{`integer`, integer, Int(150000), ``, `150.00K`},
+		{`integer`, integer, Int(3798600), ``, `3.80M`},
+		{`integer`, integer, Int(2.5e9), ``, `2.50B`},
+		{`integer`, integer, NullInt{}, ``, `-`},
+		{`integer`, integer, Float(2399157.7), ``, `2.40M`},
+	}
+	for _, test := range tests {
+		if got := test.format(test.value, test.code); got != test.want {
+			t.Errorf(`%s(%v, %q): got %s, want %s`, test.name, test.value, test.code, got, test.want)
+		}
+	}
+}
+
+func TestSortMixedMagnitudes(t *testing.T) {
+	stocks := []Stock{
+		{Ticker: `A`, Volume: Int(1200), LastTrade: Float(0.0123)},
+		{Ticker: `B`, Volume: Int(999), LastTrade: Float(1200)},
+		{Ticker: `C`, Volume: NullInt{}, LastTrade: NullFloat{}},
+		{Ticker: `D`, Volume: Int(2.5e9), LastTrade: Float(0.5)},
+		{Ticker: `E`, Volume: Int(150000), LastTrade: Float(999)},
+		{Ticker: `F`, Volume: Int(0), LastTrade: Float(0)},
+	}
+	tests := []struct {
+		name string
+		key  SortKey
+		want string
+	}{
+		{`volume ascending`, SortKey{Column: `Volume`}, `C F B A E D`},
+		{`volume descending`, SortKey{Column: `Volume`, Descending: true}, `D E A B F C`},
+		{`price ascending`, SortKey{Column: `LastTrade`}, `C F A D E B`},
+		{`price descending`, SortKey{Column: `LastTrade`, Descending: true}, `B E D A F C`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted := append([]Stock(nil), stocks...)
+			NewSorter(&Profile{Watchlist: &Watchlist{}}).Sort(sorted, []SortKey{test.key})
+
+			var tickers []string
+			for _, stock := range sorted {
+				tickers = append(tickers, stock.Ticker)
+			}
+			if got := strings.Join(tickers, ` `); got != test.want {
+				t.Errorf(`got %s, want %s`, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNullValues(t *testing.T) {
+	tests := []struct {
+		a, b NullFloat
+		less bool
+	}{
+		{Float(999), Float(1200), true},
+		{Float(1200), Float(999), false},
+		{NullFloat{}, Float(-1e12), true},
+		{Float(-1e12), NullFloat{}, false},
+		{NullFloat{}, NullFloat{}, false},
+		{Float(0), NullFloat{}, false},
+	}
+	for _, test := range tests {
+		if got := test.a.Less(test.b); got != test.less {
+			t.Errorf(`%v < %v: got %v`, test.a, test.b, got)
+		}
+		a, b := NullInt{int64(test.a.Value), test.a.Valid}, NullInt{int64(test.b.Value), test.b.Valid}
+		if got := a.Less(b); got != test.less {
+			t.Errorf(`%v < %v: got %v`, a, b, got)
+		}
+	}
+
+	for _, str := range []string{`null`, `0`, `0.0123`, `-2500000000`} {
+		var f NullFloat
+		if err := json.Unmarshal([]byte(str), &f); err != nil {
+			t.Fatal(err)
+		}
+		if f.Valid != (str != `null`) {
+			t.Errorf(`%s: got %+v`, str, f)
+		}
+		if data, _ := json.Marshal(f); string(data) != str {
+			t.Errorf(`%s: got %s back`, str, data)
+		}
+	}
+}
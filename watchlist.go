@@ -0,0 +1,82 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+)
+
+// Watchlist is a named list of stock tickers. Each watchlist has its own
+// sort order, grouping, and filter.
+type Watchlist struct {
+	Name             string                         // Watchlist name as shown in the tab bar.
+	Tickers          []string                       // List of stock tickers to display.
+	SortColumn       int                            // Column number by which we sort stock quotes.
+	Ascending        bool                           // True when sort order is ascending.
+	SortKeys         []SortKey                      `json:",omitempty"` // Sort keys, most significant first, used instead of the sort column if set.
+	SortNA           string                         `json:",omitempty"` // Placement of the values that are not available: first, last, or the lowest ones by default.
+	Grouped          bool                           // True when stocks are grouped by advancing/declining.
+	GroupBy          string                         `json:",omitempty"` // Column or filter variable to group stocks by, ex. market.
+	Collapsed        []string                       `json:",omitempty"` // Names of the groups that show the group header only.
+	Filter           string                         // Filter in human form
+	filterExpression *govaluate.EvaluableExpression // The filter as a govaluate expression
+}
+
+// NewWatchlist returns empty watchlist sorted by ticker name.
+func NewWatchlist(name string) *Watchlist {
+	return &Watchlist{Name: name, Tickers: []string{}, Ascending: true}
+}
+
+// AddWatchlist creates new empty watchlist and makes it active.
+func (profile *Profile) AddWatchlist(name string) error {
+	name = strings.TrimSpace(name)
+	if name == `` {
+		return fmt.Errorf("watchlist name can't be blank")
+	}
+	for _, watchlist := range profile.Watchlists {
+		if strings.EqualFold(watchlist.Name, name) {
+			return fmt.Errorf("watchlist `%s` already exists", watchlist.Name)
+		}
+	}
+
+	profile.Watchlists = append(profile.Watchlists, NewWatchlist(name))
+	return profile.SelectWatchlist(len(profile.Watchlists) - 1)
+}
+
+// RemoveWatchlist deletes active watchlist and activates the previous one.
+// The last remaining watchlist can't be deleted.
+func (profile *Profile) RemoveWatchlist() error {
+	if len(profile.Watchlists) < 2 {
+		return fmt.Errorf("can't delete the only watchlist")
+	}
+
+	i := profile.ActiveWatchlist
+	profile.Watchlists = append(profile.Watchlists[:i], profile.Watchlists[i+1:]...)
+	if i > 0 {
+		i--
+	}
+	return profile.SelectWatchlist(i)
+}
+
+// SelectWatchlist activates the watchlist with the given index.
+func (profile *Profile) SelectWatchlist(i int) error {
+	if i < 0 || i >= len(profile.Watchlists) {
+		return fmt.Errorf("no watchlist #%d", i+1)
+	}
+
+	profile.ActiveWatchlist = i
+	profile.Watchlist = profile.Watchlists[i]
+	return profile.Save()
+}
+
+// NextWatchlist activates the watchlist next to the active one, wrapping
+// around at the ends of the list. Negative step goes backwards.
+func (profile *Profile) NextWatchlist(step int) error {
+	count := len(profile.Watchlists)
+	return profile.SelectWatchlist(((profile.ActiveWatchlist+step)%count + count) % count)
+}
@@ -9,24 +9,36 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type YahooProvider struct {
+	sync.Mutex
 	cookies string
 	crumb   string
 	errors  string
+	metrics *Metrics
 }
 
 // NewYahooProvider creates a new instance of YahooProvider.
 func NewYahooProvider() *YahooProvider {
-	return &YahooProvider{}
+	return &YahooProvider{metrics: NewMetrics()}
+}
+
+// Metrics returns request metrics collected by the provider.
+func (yp *YahooProvider) Metrics() *Metrics {
+	return yp.metrics
 }
 
 // Initialize ensures that the provider has the necessary cookies and crumb
-// required for making authenticated requests to Yahoo Finance.
+// required for making authenticated requests to Yahoo Finance. It's safe to
+// call from concurrent requests.
 func (yp *YahooProvider) Initialize() error {
+	yp.Lock()
+	defer yp.Unlock()
+
 	var err error
 	if yp.cookies == "" {
 		yp.cookies, err = fetchCookies()
@@ -36,6 +48,7 @@ func (yp *YahooProvider) Initialize() error {
 		}
 	}
 	if yp.crumb == "" {
+		yp.metrics.crumbRefreshed()
 		yp.crumb, err = fetchCrumb(yp.cookies)
 		if err != nil {
 			yp.errors = fmt.Sprintf("Error fetching crumb: %v", err)
@@ -45,18 +58,21 @@ func (yp *YahooProvider) Initialize() error {
 	return nil
 }
 
-// FetchMarket retrieves the broader market indices (Dow, NASDAQ, etc.) and
-// commodities (Oil, Gold, etc.) data from Yahoo Finance.
-func (yp *YahooProvider) FetchMarket() (*MarketData, error) {
-	if err := yp.Initialize(); err != nil {
+// FetchMarket retrieves the quotes of the market summary symbols, ex. broader
+// market indices (Dow, NASDAQ, etc.) and commodities (Oil, Gold, etc.), from
+// Yahoo Finance.
+func (yp *YahooProvider) FetchMarket(symbols []string) (market *MarketData, err error) {
+	defer yp.metrics.observe(`market`, time.Now(), &err)
+	defer yp.expire(&err)
+
+	cookies, crumb, err := yp.session()
+	if err != nil {
 		return nil, err
 	}
 
-	symbols := `^DJI,^IXIC,^GSPC,^N225,^HSI,^FTSE,^GDAXI,^TNX,CL=F,JPY=X,EUR=X,GC=F`
-	base := `https://query1.finance.yahoo.com/v7/finance/quote`
 	params := `&range=1d&interval=5m&indicators=close&includeTimestamps=false` +
 		`&includePrePost=false&corsDomain=finance.yahoo.com&.tsrc=finance`
-	url := fmt.Sprintf(`%s?crumb=%s&symbols=%s%s`, base, yp.crumb, symbols, params)
+	url := fmt.Sprintf(`%s?crumb=%s&symbols=%s%s`, quoteURL, crumb, strings.Join(symbols, `,`), params)
 
 	client := http.Client{}
 	request, err := http.NewRequest(http.MethodGet, url, nil)
@@ -69,7 +85,7 @@ func (yp *YahooProvider) FetchMarket() (*MarketData, error) {
 		"Accept-Language": {"en-US,en;q=0.5"},
 		"Connection":      {"keep-alive"},
 		"Content-Type":    {"application/json"},
-		"Cookie":          {yp.cookies},
+		"Cookie":          {cookies},
 		"Host":            {"query1.finance.yahoo.com"},
 		"Origin":          {"https://finance.yahoo.com"},
 		"Referer":         {"https://finance.yahoo.com"},
@@ -91,75 +107,46 @@ func (yp *YahooProvider) FetchMarket() (*MarketData, error) {
 		return nil, err
 	}
 
-	return yp.extractMarket(body)
-}
-
-// assignMarket is a helper that extracts price and change data for a single
-// market item at a specific position in the API response results.
-func assignMarket(results []map[string]interface{}, position int, changeAsPercent bool) map[string]string {
-	out := make(map[string]string)
-	if len(results) <= position {
-		return out
-	}
-	if val, ok := results[position]["regularMarketChange"].(float64); ok {
-		out[`change`] = float2Str(val)
-	} else {
-		out[`change`] = "N/A"
-	}
-	if val, ok := results[position]["regularMarketPrice"].(float64); ok {
-		out[`latest`] = float2Str(val)
-	} else {
-		out[`latest`] = "N/A"
-	}
-
-	if changeAsPercent {
-		if val, ok := results[position]["regularMarketChangePercent"].(float64); ok {
-			out[`change`] = float2Str(val) + `%`
-		}
-	} else {
-		if val, ok := results[position]["regularMarketChangePercent"].(float64); ok {
-			out[`percent`] = float2Str(val)
-		} else {
-			out[`percent`] = "N/A"
-		}
-	}
-	return out
+	return extractMarket(body)
 }
 
 // extractMarket parses the raw JSON body from the market API and maps it
 // into the internal MarketData structure.
-func (yp *YahooProvider) extractMarket(body []byte) (*MarketData, error) {
-	d := map[string]map[string][]map[string]interface{}{}
-	err := json.Unmarshal(body, &d)
+func extractMarket(body []byte) (*MarketData, error) {
+	results, err := decodeResults(body)
 	if err != nil {
 		return nil, err
 	}
-	results, ok := d["quoteResponse"]["result"]
-	if !ok || len(results) == 0 {
+	if len(results) == 0 {
 		return nil, fmt.Errorf("no results found")
 	}
 
-	market := &MarketData{}
-	market.Dow = assignMarket(results, 0, false)
-	market.Nasdaq = assignMarket(results, 1, false)
-	market.Sp500 = assignMarket(results, 2, false)
-	market.Tokyo = assignMarket(results, 3, false)
-	market.HongKong = assignMarket(results, 4, false)
-	market.London = assignMarket(results, 5, false)
-	market.Frankfurt = assignMarket(results, 6, false)
-	market.Yield = make(map[string]string)
-	market.Yield[`name`] = `10-year Yield`
-	yieldMap := assignMarket(results, 7, false)
-	for k, v := range yieldMap {
-		market.Yield[k] = v
-	}
+	return buildMarket(results), nil
+}
 
-	market.Oil = assignMarket(results, 8, true)
-	market.Yen = assignMarket(results, 9, true)
-	market.Euro = assignMarket(results, 10, true)
-	market.Gold = assignMarket(results, 11, true)
+// buildMarket maps decoded market results into the internal MarketData
+// structure.
+func buildMarket(results []map[string]interface{}) *MarketData {
+	market := &MarketData{Quotes: make([]MarketQuote, len(results))}
+	for i, result := range results {
+		market.Quotes[i] = MarketQuote{
+			Symbol:    stringField(result, "symbol"),
+			Price:     floatField(result, "regularMarketPrice"),
+			Change:    floatField(result, "regularMarketChange"),
+			ChangePct: floatField(result, "regularMarketChangePercent"),
+		}
+		// Use the state of U.S. indices if Yahoo reports it, and NYSE
+		// calendar otherwise.
+		if market.State == `` && ExchangeFor(market.Quotes[i].Symbol) == nyse {
+			market.State = marketState(stringField(result, "marketState"))
+		}
+	}
+	if market.State == `` {
+		market.State = nyse.Session(time.Now())
+	}
+	market.IsClosed = market.State == SessionClosed
 
-	return market, nil
+	return market
 }
 
 // chunkTickers splits a large slice of tickers into smaller chunks to avoid
@@ -178,23 +165,26 @@ func chunkTickers(tickers []string, chunkSize int) [][]string {
 
 // FetchQuotes retrieves detailed stock quote information for the requested
 // list of tickers from Yahoo Finance.
-func (yp *YahooProvider) FetchQuotes(tickers []string) ([]Stock, error) {
+func (yp *YahooProvider) FetchQuotes(tickers []string) (allStocks []Stock, err error) {
 	if len(tickers) == 0 {
 		return []Stock{}, nil
 	}
-	if err := yp.Initialize(); err != nil {
+	defer yp.metrics.observe(`quotes`, time.Now(), &err)
+	defer yp.expire(&err)
+
+	cookies, crumb, err := yp.session()
+	if err != nil {
 		return nil, err
 	}
 
 	chunks := chunkTickers(tickers, 500)
-	var allStocks []Stock
+	yp.metrics.chunked(len(chunks))
 
 	for _, chunk := range chunks {
 		symbols := strings.Join(chunk, `,`)
-		base := `https://query1.finance.yahoo.com/v7/finance/quote`
 		params := `&range=1d&interval=5m&indicators=close&includeTimestamps=false` +
 			`&includePrePost=false&corsDomain=finance.yahoo.com&.tsrc=finance`
-		url := fmt.Sprintf(`%s?crumb=%s&symbols=%s%s`, base, yp.crumb, symbols, params)
+		url := fmt.Sprintf(`%s?crumb=%s&symbols=%s%s`, quoteURL, crumb, symbols, params)
 
 		client := http.Client{}
 		request, err := http.NewRequest(http.MethodGet, url, nil)
@@ -207,7 +197,7 @@ func (yp *YahooProvider) FetchQuotes(tickers []string) ([]Stock, error) {
 			"Accept-Language": {"en-US,en;q=0.5"},
 			"Connection":      {"keep-alive"},
 			"Content-Type":    {"application/json"},
-			"Cookie":          {yp.cookies},
+			"Cookie":          {cookies},
 			"Host":            {"query1.finance.yahoo.com"},
 			"Origin":          {"https://finance.yahoo.com"},
 			"Referer":         {"https://finance.yahoo.com"},
@@ -229,7 +219,7 @@ func (yp *YahooProvider) FetchQuotes(tickers []string) ([]Stock, error) {
 			return nil, err
 		}
 
-		stocks, err := yp.parseQuotes(body)
+		stocks, err := parseQuotes(body)
 		if err != nil {
 			return nil, err
 		}
@@ -239,90 +229,151 @@ func (yp *YahooProvider) FetchQuotes(tickers []string) ([]Stock, error) {
 	return allStocks, nil
 }
 
-// parseQuotes unmarshals the raw JSON response from Yahoo and converts each
-// result into a Stock struct, including calculating color indicators.
-func (yp *YahooProvider) parseQuotes(body []byte) ([]Stock, error) {
-	d := map[string]map[string][]map[string]interface{}{}
+// expire drops the cookies and crumb when Yahoo rejects them so that they
+// get fetched again on the next request.
+func (yp *YahooProvider) expire(err *error) {
+	if e, ok := (*err).(*yahooError); ok && e.code == `Unauthorized` {
+		yp.Lock()
+		yp.cookies, yp.crumb = "", ""
+		yp.Unlock()
+	}
+}
+
+// Returns the cookies and crumb to make the requests with, fetching them
+// first if needed.
+// -----------------------------------------------------------------------------
+func (yp *YahooProvider) session() (cookies, crumb string, err error) {
+	if err = yp.Initialize(); err != nil {
+		return
+	}
+	yp.Lock()
+	defer yp.Unlock()
+
+	return yp.cookies, yp.crumb, nil
+}
+
+// yahooError is the error reported by Yahoo in the body of the response.
+type yahooError struct {
+	code        string
+	description string
+}
+
+func (e *yahooError) Error() string {
+	return e.code + `: ` + e.description
+}
+
+// decodeResults unmarshals the raw JSON response from Yahoo and returns the
+// list of quote results. The error reported by Yahoo, if any, is returned as
+// an error.
+func decodeResults(body []byte) ([]map[string]interface{}, error) {
+	d := map[string]struct {
+		Result []map[string]interface{} `json:"result"`
+		Error  *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	}{}
 	err := json.Unmarshal(body, &d)
 	if err != nil {
 		return nil, err
 	}
-	results, ok := d["quoteResponse"]["result"]
+	for _, section := range []string{"quoteResponse", "finance"} {
+		if e := d[section].Error; e != nil {
+			return nil, &yahooError{e.Code, e.Description}
+		}
+	}
+	response, ok := d["quoteResponse"]
 	if !ok {
 		return nil, fmt.Errorf("no results found")
 	}
 
+	return response.Result, nil
+}
+
+// parseQuotes unmarshals the raw JSON response from Yahoo and converts each
+// result into a Stock struct, including calculating color indicators.
+func parseQuotes(body []byte) ([]Stock, error) {
+	results, err := decodeResults(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildStocks(results), nil
+}
+
+// buildStocks converts decoded quote results into Stock structs.
+func buildStocks(results []map[string]interface{}) []Stock {
 	stocks := make([]Stock, len(results))
-	for i, raw := range results {
-		result := map[string]string{}
-		for k, v := range raw {
-			switch v := v.(type) {
-			case string:
-				result[k] = v
-			case float64:
-				result[k] = float2Str(v)
-			default:
-				result[k] = fmt.Sprintf("%v", v)
-			}
-		}
-		stocks[i].Ticker = result["symbol"]
-		stocks[i].LastTrade = result["regularMarketPrice"]
-		stocks[i].Change = result["regularMarketChange"]
-		stocks[i].ChangePct = result["regularMarketChangePercent"]
-		stocks[i].Open = result["regularMarketOpen"]
-		stocks[i].Low = result["regularMarketDayLow"]
-		stocks[i].High = result["regularMarketDayHigh"]
-		stocks[i].Low52 = result["fiftyTwoWeekLow"]
-		stocks[i].High52 = result["fiftyTwoWeekHigh"]
-		stocks[i].Volume = result["regularMarketVolume"]
-		stocks[i].AvgVolume = result["averageDailyVolume10Day"]
-		stocks[i].PeRatio = result["trailingPE"]
-		stocks[i].PeRatioX = result["trailingPE"]
-		stocks[i].Dividend = result["trailingAnnualDividendRate"]
-
-		val, err := strconv.ParseFloat(result["trailingAnnualDividendYield"], 64)
-		if err != nil {
-			stocks[i].Yield = "N/A"
-		} else {
-			stocks[i].Yield = strconv.FormatFloat(val*100, 'f', 2, 64)
+	for i, result := range results {
+		stocks[i].Ticker = stringField(result, "symbol")
+		stocks[i].LastTrade = floatField(result, "regularMarketPrice")
+		stocks[i].Change = floatField(result, "regularMarketChange")
+		stocks[i].ChangePct = floatField(result, "regularMarketChangePercent")
+		stocks[i].Open = floatField(result, "regularMarketOpen")
+		stocks[i].Low = floatField(result, "regularMarketDayLow")
+		stocks[i].High = floatField(result, "regularMarketDayHigh")
+		stocks[i].Low52 = floatField(result, "fiftyTwoWeekLow")
+		stocks[i].High52 = floatField(result, "fiftyTwoWeekHigh")
+		stocks[i].Volume = intField(result, "regularMarketVolume")
+		stocks[i].AvgVolume = intField(result, "averageDailyVolume10Day")
+		stocks[i].PeRatio = floatField(result, "trailingPE")
+		stocks[i].PeRatioX = floatField(result, "trailingPE")
+		stocks[i].Dividend = floatField(result, "trailingAnnualDividendRate")
+
+		if yield := floatField(result, "trailingAnnualDividendYield"); yield.Valid {
+			stocks[i].Yield = Float(yield.Value * 100)
 		}
 
-		stocks[i].MarketCap = result["marketCap"]
-		stocks[i].MarketCapX = result["marketCap"]
-		stocks[i].Currency = result["currency"]
-		stocks[i].PreOpen = result["preMarketChangePercent"]
-		stocks[i].AfterHours = result["postMarketChangePercent"]
-
-		adv, err := strconv.ParseFloat(stocks[i].Change, 64)
-		stocks[i].Direction = 0
-		stocks[i].RowColor = ""
-		if err == nil {
-			if adv < 0.0 {
-				stocks[i].Direction = -1
-				stocks[i].RowColor = "loss"
-			} else if adv > 0.0 {
-				stocks[i].Direction = 1
-				stocks[i].RowColor = "gain"
-			}
+		stocks[i].MarketCap = intField(result, "marketCap")
+		stocks[i].MarketCapX = intField(result, "marketCap")
+		stocks[i].Currency = stringField(result, "currency")
+		stocks[i].PreOpen = floatField(result, "preMarketChangePercent")
+		stocks[i].AfterHours = floatField(result, "postMarketChangePercent")
+		stocks[i].Time = timeField(result, "regularMarketTime")
+		stocks[i].PrePrice = floatField(result, "preMarketPrice")
+		stocks[i].PreChange = floatField(result, "preMarketChange")
+		stocks[i].PreTime = timeField(result, "preMarketTime")
+		stocks[i].PostPrice = floatField(result, "postMarketPrice")
+		stocks[i].PostChange = floatField(result, "postMarketChange")
+		stocks[i].PostTime = timeField(result, "postMarketTime")
+		stocks[i].MarketState = marketState(stringField(result, "marketState"))
+		if exchange := ExchangeFor(stocks[i].Ticker); stocks[i].MarketState == `` && exchange != nil {
+			stocks[i].MarketState = exchange.Session(time.Now())
 		}
 
-		if pre, err := strconv.ParseFloat(stocks[i].PreOpen, 64); err == nil {
-			if pre < 0.0 {
-				stocks[i].PreOpenColor = "loss"
-			} else if pre > 0.0 {
-				stocks[i].PreOpenColor = "gain"
-			}
-		}
+		stocks[i].Direction = direction(stocks[i].Change)
+		stocks[i].Fields = result
+	}
+	return stocks
+}
 
-		if aft, err := strconv.ParseFloat(stocks[i].AfterHours, 64); err == nil {
-			if aft < 0.0 {
-				stocks[i].AfterHoursColor = "loss"
-			} else if aft > 0.0 {
-				stocks[i].AfterHoursColor = "gain"
-			}
+// direction returns -1 when the change is negative, 1 when it's positive,
+// and 0 when there is no change or it's not available.
+func direction(change NullFloat) int {
+	if change.Valid {
+		if change.Value < 0.0 {
+			return -1
+		} else if change.Value > 0.0 {
+			return 1
 		}
 	}
-	return stocks, nil
+	return 0
+}
+
+// marketState converts Yahoo market state such as PREPRE or POST to the
+// trading session state. Unknown states are returned blank.
+func marketState(state string) string {
+	switch state {
+	case `PRE`:
+		return SessionPre
+	case `REGULAR`:
+		return SessionRegular
+	case `POST`:
+		return SessionPost
+	case `PREPRE`, `POSTPOST`, `CLOSED`:
+		return SessionClosed
+	}
+	return ``
 }
 
 // float2Str converts a float64 to a human-readable string with units (K, M, B, T)
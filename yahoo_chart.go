@@ -0,0 +1,131 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// FetchChart retrieves price and volume history of the given ticker over
+// the given range from Yahoo Finance chart API.
+func (yp *YahooProvider) FetchChart(ticker, span string) (chart *Chart, err error) {
+	defer yp.metrics.observe(`chart`, time.Now(), &err)
+	defer yp.expire(&err)
+
+	interval, ok := chartIntervals[span]
+	if !ok {
+		return nil, fmt.Errorf("unknown chart range `%s`", span)
+	}
+	cookies, crumb, err := yp.session()
+	if err != nil {
+		return nil, err
+	}
+
+	base := `https://query1.finance.yahoo.com/v8/finance/chart/` + ticker
+	params := `&includePrePost=false&corsDomain=finance.yahoo.com&.tsrc=finance`
+	url := fmt.Sprintf(`%s?crumb=%s&range=%s&interval=%s%s`, base, crumb, span, interval, params)
+
+	client := http.Client{}
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header = http.Header{
+		"Accept":          {"*/*"},
+		"Accept-Language": {"en-US,en;q=0.5"},
+		"Connection":      {"keep-alive"},
+		"Content-Type":    {"application/json"},
+		"Cookie":          {cookies},
+		"Host":            {"query1.finance.yahoo.com"},
+		"Origin":          {"https://finance.yahoo.com"},
+		"Referer":         {"https://finance.yahoo.com"},
+		"Sec-Fetch-Dest":  {"empty"},
+		"Sec-Fetch-Mode":  {"cors"},
+		"Sec-Fetch-Site":  {"same-site"},
+		"TE":              {"trailers"},
+		"User-Agent":      {userAgent},
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractChart(ticker, span, body)
+}
+
+// extractChart parses the raw JSON body from the chart API into Chart. The
+// data points with no close price are skipped.
+func extractChart(ticker, span string, body []byte) (*Chart, error) {
+	d := struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					Currency           string   `json:"currency"`
+					ChartPreviousClose *float64 `json:"chartPreviousClose"`
+					PreviousClose      *float64 `json:"previousClose"`
+				} `json:"meta"`
+				Timestamp  []int64 `json:"timestamp"`
+				Indicators struct {
+					Quote []struct {
+						Close  []*float64 `json:"close"`
+						Volume []*float64 `json:"volume"`
+					} `json:"quote"`
+				} `json:"indicators"`
+			} `json:"result"`
+			Error *struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}{}
+	if err := json.Unmarshal(body, &d); err != nil {
+		return nil, err
+	}
+	if e := d.Chart.Error; e != nil {
+		return nil, &yahooError{e.Code, e.Description}
+	}
+	if len(d.Chart.Result) == 0 {
+		return nil, fmt.Errorf("no chart data for %s", ticker)
+	}
+
+	result := d.Chart.Result[0]
+	chart := &Chart{Ticker: ticker, Range: span, Currency: result.Meta.Currency}
+	if close := result.Meta.ChartPreviousClose; close != nil {
+		chart.PreviousClose = Float(*close)
+	} else if close := result.Meta.PreviousClose; close != nil {
+		chart.PreviousClose = Float(*close)
+	}
+	if len(result.Indicators.Quote) == 0 {
+		return chart, nil
+	}
+
+	quote := result.Indicators.Quote[0]
+	for i, timestamp := range result.Timestamp {
+		if i >= len(quote.Close) || quote.Close[i] == nil {
+			continue
+		}
+		volume := NullInt{}
+		if i < len(quote.Volume) && quote.Volume[i] != nil {
+			volume = Int(int64(*quote.Volume[i]))
+		}
+		chart.Times = append(chart.Times, time.Unix(timestamp, 0))
+		chart.Close = append(chart.Close, *quote.Close[i])
+		chart.Volume = append(chart.Volume, volume)
+	}
+
+	return chart, nil
+}
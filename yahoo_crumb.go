@@ -16,12 +16,18 @@ import (
 )
 
 const (
-	crumbURL     = "https://query1.finance.yahoo.com/v1/test/getcrumb"
-	cookieURL    = "https://finance.yahoo.com/"
 	userAgent    = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:109.0) Gecko/20100101 Firefox/113.0"
 	euConsentURL = "https://consent.yahoo.com/v2/collectConsent?sessionId="
 )
 
+// Yahoo Finance endpoints. These are variables so that tests can point them
+// to a local server.
+var (
+	crumbURL  = "https://query1.finance.yahoo.com/v1/test/getcrumb"
+	cookieURL = "https://finance.yahoo.com/"
+	quoteURL  = "https://query1.finance.yahoo.com/v7/finance/quote"
+)
+
 // fetchCrumb retrieves a unique "crumb" string from Yahoo Finance, which is
 // required as a security parameter for API requests.
 func fetchCrumb(cookies string) (string, error) {
@@ -56,6 +62,9 @@ func fetchCrumb(cookies string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetchCrumb: %s", response.Status)
+	}
 
 	return string(body), nil
 }
@@ -0,0 +1,102 @@
+// Copyright (c) 2013-2026 by Michael Dvorkin and contributors. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package mop
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// yahooStub is the Yahoo Finance stand-in that hands out cookies and crumbs,
+// and rejects the quote requests made with a crumb other than the latest.
+type yahooStub struct {
+	sync.Mutex
+	cookies  int    // Number of cookies handed out.
+	crumbs   int    // Number of crumbs handed out.
+	valid    string // Crumb accepted by the quote requests.
+	throttle bool   // Reject the next crumb request as if rate limited.
+}
+
+func (stub *yahooStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stub.Lock()
+	defer stub.Unlock()
+
+	switch r.URL.Path {
+	case `/`:
+		stub.cookies++
+		http.SetCookie(w, &http.Cookie{Name: `A1`, Value: fmt.Sprintf(`session%d`, stub.cookies)})
+	case `/getcrumb`:
+		if !strings.Contains(r.Header.Get(`Cookie`), fmt.Sprintf(`A1=session%d`, stub.cookies)) {
+			http.Error(w, `Unauthorized`, http.StatusUnauthorized)
+			return
+		}
+		if stub.throttle {
+			stub.throttle = false
+			http.Error(w, `Too Many Requests`, http.StatusTooManyRequests)
+			return
+		}
+		stub.crumbs++
+		stub.valid = fmt.Sprintf(`crumb%d`, stub.crumbs)
+		fmt.Fprint(w, stub.valid)
+	case `/quote`:
+		if r.URL.Query().Get(`crumb`) != stub.valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"finance":{"result":null,"error":{"code":"Unauthorized","description":"Invalid Crumb"}}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"quoteResponse":{"result":[{"symbol":%q,"regularMarketPrice":190.5}],"error":null}}`, r.URL.Query().Get(`symbols`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestYahooCrumb(t *testing.T) {
+	stub := &yahooStub{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	defer func(crumb, cookie, quote string) {
+		crumbURL, cookieURL, quoteURL = crumb, cookie, quote
+	}(crumbURL, cookieURL, quoteURL)
+	crumbURL, cookieURL, quoteURL = server.URL+`/getcrumb`, server.URL+`/`, server.URL+`/quote`
+
+	yp := NewYahooProvider()
+	tests := []struct {
+		name    string
+		prepare func()
+		err     string // Error expected from the quotes, if any.
+		cookies int    // Number of cookies fetched so far.
+		crumbs  int    // Number of crumbs fetched so far.
+	}{
+		{`first request fetches the crumb`, func() {}, ``, 1, 1},
+		{`the crumb is reused`, func() {}, ``, 1, 1},
+		{`expired crumb`, func() { stub.valid = `expired` }, `Unauthorized: Invalid Crumb`, 1, 1},
+		{`the crumb is fetched again`, func() {}, ``, 2, 2},
+		{`rate limited crumb`, func() { stub.valid, stub.throttle = `expired`, true }, `Unauthorized: Invalid Crumb`, 2, 2},
+		{`failed crumb request`, func() {}, `fetchCrumb: 429 Too Many Requests`, 3, 2},
+		{`the crumb is fetched after the failure`, func() {}, ``, 3, 3},
+	}
+	for _, test := range tests {
+		stub.Lock()
+		test.prepare()
+		stub.Unlock()
+
+		stocks, err := yp.FetchQuotes([]string{`AAPL`})
+		if test.err != `` {
+			if err == nil || err.Error() != test.err {
+				t.Errorf(`%s: got error %v, want %s`, test.name, err, test.err)
+			}
+		} else if err != nil || len(stocks) != 1 || stocks[0].Ticker != `AAPL` || stocks[0].LastTrade != Float(190.5) {
+			t.Errorf(`%s: got %+v (%v)`, test.name, stocks, err)
+		}
+		if stub.cookies != test.cookies || stub.crumbs != test.crumbs {
+			t.Errorf(`%s: got %d cookies and %d crumbs, want %d and %d`, test.name, stub.cookies, stub.crumbs, test.cookies, test.crumbs)
+		}
+	}
+}